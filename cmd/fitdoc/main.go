@@ -0,0 +1,207 @@
+// Command fitdoc generates a file registering the doc comments of
+// a package with fit.RegisterDocs, so they describe its services,
+// methods, types and fields in /help, OpenAPI and the clients
+// generated from them. It is run by go generate.
+//
+//	//go:generate go run github.com/hyqe/ribose/cmd/fitdoc
+//
+// The comments of exported types, their fields and methods, and
+// of exported funcs are kept, e.g. Service, Service.Create and
+// User.Email. Those of aliases of unnamed structs are too, but
+// can't be found from the struct at run time.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fitPath is the import path of the package the generated file calls.
+const fitPath = "github.com/hyqe/ribose/internal/fit"
+
+func main() {
+	log.SetFlags(0)
+	out := flag.String("out", "fit_docs.go", "file to write, in the directory of the package")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: fitdoc [-out file] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	pkg, err := listPackage(dir)
+	if err != nil {
+		log.Fatalf("failed to list package: %v", err)
+	}
+	comments, err := parseComments(pkg, *out)
+	if err != nil {
+		log.Fatalf("failed to parse %v: %v", pkg.ImportPath, err)
+	}
+	src, err := generate(pkg, comments)
+	if err != nil {
+		log.Fatalf("failed to generate: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(pkg.Dir, *out), src, 0o644)
+	if err != nil {
+		log.Fatalf("failed to write %v: %v", *out, err)
+	}
+}
+
+// listedPackage is what go list says of a package.
+type listedPackage struct {
+	Dir        string
+	ImportPath string
+	Name       string
+	GoFiles    []string
+}
+
+func listPackage(dir string) (*listedPackage, error) {
+	cmd := exec.Command("go", "list", "-json", ".")
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var pkg listedPackage
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// parseComments finds the doc comments of the exported declarations
+// of pkg, skipping the file fitdoc writes.
+func parseComments(pkg *listedPackage, out string) (map[string]string, error) {
+	fset := token.NewFileSet()
+	comments := make(map[string]string)
+	add := func(name string, groups ...*ast.CommentGroup) {
+		for _, g := range groups {
+			if text := strings.TrimSpace(g.Text()); text != "" {
+				comments[name] = text
+				return
+			}
+		}
+	}
+	for _, name := range pkg.GoFiles {
+		if name == out {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				name := decl.Name.Name
+				if decl.Recv != nil {
+					recv := recvName(decl.Recv.List[0].Type)
+					if !token.IsExported(recv) {
+						continue
+					}
+					name = recv + "." + name
+				}
+				if decl.Name.IsExported() {
+					add(name, decl.Doc)
+				}
+			case *ast.GenDecl:
+				if decl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.TypeSpec)
+					if !spec.Name.IsExported() {
+						continue
+					}
+					// a lone spec is documented by the decl.
+					doc := spec.Doc
+					if len(decl.Specs) == 1 {
+						doc = decl.Doc
+					}
+					add(spec.Name.Name, doc)
+					if st, ok := spec.Type.(*ast.StructType); ok {
+						for _, field := range st.Fields.List {
+							for _, fieldName := range fieldNames(field) {
+								if token.IsExported(fieldName) {
+									add(spec.Name.Name+"."+fieldName, field.Doc, field.Comment)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return comments, nil
+}
+
+// recvName is the name of the type of a receiver, e.g. Service
+// for *Service or List for List[T].
+func recvName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// fieldNames are the names of field, which is named after its
+// type when embedded.
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) == 0 {
+		return []string{recvName(field.Type)}
+	}
+	names := make([]string, len(field.Names))
+	for i, name := range field.Names {
+		names[i] = name.Name
+	}
+	return names
+}
+
+func generate(pkg *listedPackage, comments map[string]string) ([]byte, error) {
+	names := make([]string, 0, len(comments))
+	for name := range comments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by fitdoc. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %v\n\n", pkg.Name)
+	fmt.Fprintf(&b, "import %q\n\n", fitPath)
+	fmt.Fprintf(&b, "func init() {\n")
+	fmt.Fprintf(&b, "fit.RegisterDocs(%q, map[string]string{\n", pkg.ImportPath)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%q: %q,\n", name, comments[name])
+	}
+	fmt.Fprintf(&b, "})\n")
+	fmt.Fprintf(&b, "}\n")
+	return format.Source(b.Bytes())
+}
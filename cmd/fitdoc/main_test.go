@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestGenerate compares the file generated for testdata/src/docs
+// with its fit_docs.go, which it skips when parsing, and which
+// -update rewrites.
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("testdata", "src", "docs")
+	pkg, err := listPackage(dir)
+	if err != nil {
+		t.Fatalf("failed to list package: %v", err)
+	}
+	comments, err := parseComments(pkg, "fit_docs.go")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	got, err := generate(pkg, comments)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	golden := filepath.Join(dir, "fit_docs.go")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run with -update: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated file differs from %v, run with -update and review the diff:\n%s", golden, got)
+	}
+}
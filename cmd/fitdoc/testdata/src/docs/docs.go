@@ -0,0 +1,65 @@
+// Package docs has declarations of every kind fitdoc documents.
+package docs
+
+import "context"
+
+// Service manages things.
+type Service struct{}
+
+// Create adds a thing.
+//
+// Its name must be unique.
+func (s *Service) Create(ctx context.Context, in *Thing) (*Thing, error) { return in, nil }
+
+// Get finds a thing. */ is escaped by the generated clients.
+func (Service) Get(ctx context.Context, in *Thing) (*Thing, error) { return in, nil }
+
+// internal isn't exported, so it isn't kept.
+func (Service) internal() {}
+
+// Thing is a thing.
+type Thing struct {
+	// Name is what it is called.
+	Name string `json:"name"`
+	Size int    `json:"size"` // Size is in bytes.
+	// Meta is embedded, and named after its type.
+	Meta
+	*Owner
+	// hidden isn't exported.
+	hidden string
+}
+
+type (
+	// Meta is grouped with Owner, so each keeps its own comment.
+	Meta struct {
+		// Tags label it.
+		Tags []string `json:"tags"`
+	}
+	// Owner has it.
+	Owner struct {
+		Email string `json:"email"` // Email reaches them.
+	}
+)
+
+// List is a generic type.
+type List[T any] struct {
+	// Items are in order.
+	Items []T `json:"items"`
+}
+
+// Len counts the items.
+func (l List[T]) Len() int { return len(l.Items) }
+
+// Request is an alias of an unnamed struct.
+type Request = struct {
+	// ID is kept, but can't be found at run time.
+	ID string `json:"id"`
+}
+
+// New makes a Service.
+func New() *Service { return &Service{} }
+
+// undocumented has a comment, but isn't exported.
+type undocumented struct{}
+
+type Undocumented struct{}
@@ -0,0 +1,27 @@
+// Code generated by fitdoc. DO NOT EDIT.
+
+package docs
+
+import "github.com/hyqe/ribose/internal/fit"
+
+func init() {
+	fit.RegisterDocs("github.com/hyqe/ribose/cmd/fitdoc/testdata/src/docs", map[string]string{
+		"List":           "List is a generic type.",
+		"List.Items":     "Items are in order.",
+		"List.Len":       "Len counts the items.",
+		"Meta":           "Meta is grouped with Owner, so each keeps its own comment.",
+		"Meta.Tags":      "Tags label it.",
+		"New":            "New makes a Service.",
+		"Owner":          "Owner has it.",
+		"Owner.Email":    "Email reaches them.",
+		"Request":        "Request is an alias of an unnamed struct.",
+		"Request.ID":     "ID is kept, but can't be found at run time.",
+		"Service":        "Service manages things.",
+		"Service.Create": "Create adds a thing.\n\nIts name must be unique.",
+		"Service.Get":    "Get finds a thing. */ is escaped by the generated clients.",
+		"Thing":          "Thing is a thing.",
+		"Thing.Meta":     "Meta is embedded, and named after its type.",
+		"Thing.Name":     "Name is what it is called.",
+		"Thing.Size":     "Size is in bytes.",
+	})
+}
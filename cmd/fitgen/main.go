@@ -0,0 +1,103 @@
+// Command fitgen generates a TypeScript client for a fit service
+// from the docs it serves at /help.
+//
+//	go run ./cmd/fitgen -url http://localhost/users.Service -out users.ts
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/hyqe/ribose/internal/fit"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	serviceURL := flag.String("url", "", "url of the service, e.g. http://localhost/users.Service")
+	out := flag.String("out", "", "file to write, defaults to stdout")
+	flag.Parse()
+
+	if *serviceURL == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	svc, err := loadService(*serviceURL)
+	if err != nil {
+		log.Fatalf("failed to load service: %v", err)
+	}
+
+	src := generateTypeScript(svc)
+
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	err = os.WriteFile(*out, src, 0o644)
+	if err != nil {
+		log.Fatalf("failed to write %v: %v", *out, err)
+	}
+}
+
+// service is everything served under /help for a single service.
+type service struct {
+	Name        string
+	Description string
+	Methods     []method
+}
+
+type method struct {
+	Name string
+	fit.MethodDocs
+}
+
+// loadService reads /help and the /<method>/help of each listed method.
+func loadService(serviceURL string) (*service, error) {
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return nil, err
+	}
+	svc := &service{
+		Name: path.Base(u.Path),
+	}
+
+	var docs fit.ServiceDocs
+	err = getJSON(u.JoinPath("help").String(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	svc.Description = docs.Description
+
+	for _, name := range docs.Methods {
+		m := method{Name: name}
+		err = getJSON(u.JoinPath(name, "help").String(), &m.MethodDocs)
+		if err != nil {
+			return nil, err
+		}
+		svc.Methods = append(svc.Methods, m)
+	}
+	return svc, nil
+}
+
+func getJSON(u string, v any) error {
+	resp, err := http.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %v: %v", u, resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("GET %v: failed to decode: %w", u, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyqe/ribose/internal/fit"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+type Service struct{}
+
+type User struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email" validate:"required,email"`
+	Role      string    `json:"role" validate:"oneof=admin member"`
+	Nickname  *string   `json:"nickname"`
+	Tags      []string  `json:"tags,omitempty" validate:"max=10"`
+	Manager   *User     `json:"manager"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Age   int    `json:"age,omitempty" validate:"gte=18"`
+}
+
+type GetRequest struct {
+	ID uuid.UUID `json:"id" path:"id"`
+}
+
+type WatchRequest struct{}
+
+type Change struct {
+	Op   string `json:"op" validate:"oneof=created deleted"`
+	User User   `json:"user"`
+}
+
+func (Service) Create(ctx context.Context, in *CreateRequest) (*User, status.Status) {
+	return nil, status.Created
+}
+
+func (Service) Get(ctx context.Context, in *GetRequest) (*User, status.Status) {
+	return nil, status.OK
+}
+
+func (Service) List(ctx context.Context) ([]User, status.Status) {
+	return nil, status.OK
+}
+
+func (Service) Delete(ctx context.Context, in *GetRequest) status.Status {
+	return status.OK
+}
+
+func (Service) Watch(ctx context.Context, in *WatchRequest, send func(*Change) error) status.Status {
+	return status.OK
+}
+
+func init() {
+	fit.RegisterDocs(reflect.TypeOf(Service{}).PkgPath(), map[string]string{
+		"Service":        "Service manages users.",
+		"Service.Create": "Create adds a user.\n\nIts email must be unique.",
+		"Service.Watch":  "Watch sends every change made to users.",
+		"User":           "User is someone who signed up.",
+		"User.Email":     "Email is where they are reached.",
+	})
+}
+
+// TestGenerateTypeScript compares the client generated for Service
+// with testdata/service.ts, which -update rewrites.
+func TestGenerateTypeScript(t *testing.T) {
+	rpc := fit.NewRPC(Service{},
+		fit.WithStrict(),
+		fit.WithMethod("Get", fit.Get("/{id}")),
+		fit.WithMethod("List", fit.Get("")),
+	)
+	srv := httptest.NewServer(rpc.NewNetHttpHandler())
+	defer srv.Close()
+
+	svc, err := loadService(srv.URL + "/" + rpc.Name())
+	if err != nil {
+		t.Fatalf("failed to load service: %v", err)
+	}
+	got := generateTypeScript(svc)
+
+	golden := filepath.Join("testdata", "service.ts")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run with -update: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated client differs from %v, run with -update and review the diff:\n%s", golden, got)
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hyqe/ribose/internal/fit"
+)
+
+// generateTypeScript writes an interface for every type of the
+// service, and a fetch based client with a method for each of
+// its methods.
+func generateTypeScript(svc *service) []byte {
+	g := &tsGenerator{
+		defs:  make(map[string]*fit.Schema),
+		names: make(map[string]string),
+	}
+	for _, m := range svc.Methods {
+		for name, def := range m.Defs {
+			g.defs[name] = def
+		}
+	}
+	g.nameDefs()
+
+	fmt.Fprintf(&g.buf, "// Code generated by fitgen. DO NOT EDIT.\n")
+	fmt.Fprintf(&g.buf, "// source: %v\n", svc.Name)
+
+	defNames := make([]string, 0, len(g.defs))
+	for name := range g.defs {
+		defNames = append(defNames, name)
+	}
+	sort.Strings(defNames)
+	for _, name := range defNames {
+		g.interfaceDecl(g.names[name], g.defs[name])
+	}
+
+	// methods whose INPUT/OUTPUT is not a named type get their own.
+	type signature struct {
+		name, doc, in, out string
+		stream             bool
+	}
+	var signatures []signature
+	for _, m := range svc.Methods {
+		sig := signature{name: m.Name, doc: m.Description, stream: m.Stream}
+		sig.in = g.namedType(m.Name+"Request", m.Request)
+		sig.out = "void"
+		if m.Response != nil {
+			sig.out = g.namedType(m.Name+"Response", m.Response)
+		}
+		signatures = append(signatures, sig)
+	}
+
+	fmt.Fprintf(&g.buf, "%v", tsRuntime)
+
+	className := pascalCase(svc.Name) + "Client"
+	fmt.Fprintf(&g.buf, "\n%v", jsComment(lines(svc.Description), ""))
+	fmt.Fprintf(&g.buf, "export class %v {\n", className)
+	fmt.Fprintf(&g.buf, "  constructor(private readonly options: ClientOptions = {}) {}\n\n")
+	fmt.Fprintf(&g.buf, "  private call<I, O>(method: string, input: I): Promise<O> {\n")
+	fmt.Fprintf(&g.buf, "    return call<I, O>(this.options, %q, method, input);\n", "/"+svc.Name+"/")
+	fmt.Fprintf(&g.buf, "  }\n")
+	for _, sig := range signatures {
+		fmt.Fprintf(&g.buf, "\n%v", jsComment(lines(sig.doc), "  "))
+		if sig.stream {
+			fmt.Fprintf(&g.buf, "  %v(input: %v): AsyncGenerator<%v> {\n", lowerCamelCase(sig.name), sig.in, sig.out)
+			fmt.Fprintf(&g.buf, "    return stream<%v, %v>(this.options, %q, %q, input);\n", sig.in, sig.out, "/"+svc.Name+"/", sig.name)
+			fmt.Fprintf(&g.buf, "  }\n")
+			continue
+		}
+		fmt.Fprintf(&g.buf, "  %v(input: %v): Promise<%v> {\n", lowerCamelCase(sig.name), sig.in, sig.out)
+		fmt.Fprintf(&g.buf, "    return this.call(%q, input);\n", sig.name)
+		fmt.Fprintf(&g.buf, "  }\n")
+	}
+	fmt.Fprintf(&g.buf, "}\n")
+
+	return g.buf.Bytes()
+}
+
+const tsRuntime = `
+/** a typed detail of an error, e.g. {"@type": "ErrorInfo", "reason": "EMAIL_TAKEN"} */
+export interface ErrorDetail {
+  "@type"?: string;
+  [key: string]: unknown;
+}
+
+export interface ErrorBody {
+  code: number;
+  message: string;
+  details?: ErrorDetail[];
+  request_id?: string;
+}
+
+export class FitError extends Error {
+  constructor(
+    readonly code: number,
+    message: string,
+    readonly details: ErrorDetail[] = [],
+    readonly requestId?: string,
+  ) {
+    super(message);
+    this.name = "FitError";
+  }
+
+  /** detail finds the first detail with the given @type. */
+  detail(type: string): ErrorDetail | undefined {
+    return this.details.find((d) => d["@type"] === type);
+  }
+}
+
+export interface ClientOptions {
+  /** url the service is served under, e.g. http://localhost */
+  baseURL?: string;
+  headers?: Record<string, string>;
+  fetch?: typeof fetch;
+}
+
+function post<I>(options: ClientOptions, prefix: string, method: string, input: I, accept: string): Promise<Response> {
+  const f = options.fetch ?? fetch;
+  return f((options.baseURL ?? "") + prefix + method, {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+      Accept: accept,
+      ...options.headers,
+    },
+    body: JSON.stringify(input),
+  });
+}
+
+function toFitError(status: number, text: string, statusText: string): FitError {
+  let body: ErrorBody | undefined;
+  try {
+    body = JSON.parse(text) as ErrorBody;
+  } catch {
+    // not an ErrorBody, e.g. from a proxy.
+  }
+  if (body && typeof body.code === "number") {
+    return new FitError(status, body.message, body.details, body.request_id);
+  }
+  return new FitError(status, text.trim() || statusText);
+}
+
+async function call<I, O>(options: ClientOptions, prefix: string, method: string, input: I): Promise<O> {
+  const resp = await post(options, prefix, method, input, "application/json");
+  const text = await resp.text();
+  if (!resp.ok) {
+    throw toFitError(resp.status, text, resp.statusText);
+  }
+  return (text ? JSON.parse(text) : {}) as O;
+}
+
+/** stream yields each line of a newline delimited JSON stream. */
+async function* stream<I, O>(options: ClientOptions, prefix: string, method: string, input: I): AsyncGenerator<O> {
+  const resp = await post(options, prefix, method, input, "application/x-ndjson");
+  if (!resp.ok || !resp.body) {
+    throw toFitError(resp.status, await resp.text(), resp.statusText);
+  }
+  const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffered = "";
+  try {
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) {
+        return;
+      }
+      buffered += value;
+      let newline: number;
+      while ((newline = buffered.indexOf("\n")) >= 0) {
+        const line = buffered.slice(0, newline).trim();
+        buffered = buffered.slice(newline + 1);
+        if (!line) {
+          continue;
+        }
+        const msg = JSON.parse(line) as { result?: O; error?: ErrorBody };
+        if (msg.error) {
+          throw new FitError(msg.error.code, msg.error.message, msg.error.details, msg.error.request_id);
+        }
+        yield msg.result as O;
+      }
+    }
+  } finally {
+    reader.cancel().catch(() => {});
+  }
+}
+`
+
+type tsGenerator struct {
+	buf   bytes.Buffer
+	defs  map[string]*fit.Schema
+	names map[string]string // $defs name -> TypeScript name
+}
+
+// nameDefs names each def after its go type, prefixing the
+// package when two packages share a type name.
+func (g *tsGenerator) nameDefs() {
+	count := make(map[string]int)
+	for name := range g.defs {
+		count[typeName(name)]++
+	}
+	for name := range g.defs {
+		if count[typeName(name)] > 1 {
+			g.names[name] = pascalCase(name)
+		} else {
+			g.names[name] = typeName(name)
+		}
+	}
+}
+
+// namedType is the type of s, declaring an interface called
+// name for it when it isn't a $ref.
+func (g *tsGenerator) namedType(name string, s *fit.Schema) string {
+	if s == nil {
+		return "unknown"
+	}
+	if s.Ref != "" {
+		return g.tsType(s, "")
+	}
+	g.interfaceDecl(name, s)
+	return name
+}
+
+func (g *tsGenerator) interfaceDecl(name string, s *fit.Schema) {
+	fmt.Fprintf(&g.buf, "\n%v", jsDoc(s, ""))
+	if s.Type.Is("object") && s.AdditionalProperties == nil {
+		fmt.Fprintf(&g.buf, "export interface %v %v\n", name, g.objectType(s, ""))
+		return
+	}
+	fmt.Fprintf(&g.buf, "export type %v = %v;\n", name, g.tsType(s, ""))
+}
+
+func (g *tsGenerator) objectType(s *fit.Schema, indent string) string {
+	if len(s.Properties) == 0 {
+		return "{}"
+	}
+	required := make(map[string]bool)
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, name := range names {
+		prop := s.Properties[name]
+		b.WriteString(jsDoc(prop, indent+"  "))
+		optional := ""
+		if !required[name] {
+			optional = "?"
+		}
+		fmt.Fprintf(&b, "%v  %v%v: %v;\n", indent, propertyName(name), optional, g.tsType(prop, indent+"  "))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+func (g *tsGenerator) tsType(s *fit.Schema, indent string) string {
+	switch {
+	case s.Ref != "":
+		name := strings.TrimPrefix(s.Ref, "#/$defs/")
+		if n, ok := g.names[name]; ok {
+			return n
+		}
+		return "unknown"
+	case s.AnyOf != nil:
+		return g.union(s.AnyOf, indent)
+	case s.Const != nil:
+		return literal(s.Const)
+	case s.Enum != nil:
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = literal(v)
+		}
+		if s.Type.Is("null") {
+			values = append(values, "null")
+		}
+		return strings.Join(values, " | ")
+	}
+
+	if len(s.Type) == 0 {
+		return "unknown"
+	}
+	types := make([]string, 0, len(s.Type))
+	for _, typ := range s.Type {
+		switch typ {
+		case "string":
+			types = append(types, "string")
+		case "integer", "number":
+			types = append(types, "number")
+		case "boolean":
+			types = append(types, "boolean")
+		case "null":
+			types = append(types, "null")
+		case "array":
+			elem := "unknown"
+			if s.Items != nil {
+				elem = g.tsType(s.Items, indent)
+			}
+			if strings.Contains(elem, "|") {
+				elem = "(" + elem + ")"
+			}
+			types = append(types, elem+"[]")
+		case "object":
+			if s.AdditionalProperties != nil {
+				types = append(types, "Record<string, "+g.tsType(s.AdditionalProperties, indent)+">")
+			} else {
+				types = append(types, g.objectType(s, indent))
+			}
+		default:
+			types = append(types, "unknown")
+		}
+	}
+	return strings.Join(types, " | ")
+}
+
+func (g *tsGenerator) union(schemas []*fit.Schema, indent string) string {
+	types := make([]string, len(schemas))
+	for i, s := range schemas {
+		types[i] = g.tsType(s, indent)
+	}
+	return strings.Join(types, " | ")
+}
+
+// jsDoc documents the description, format and validation
+// constraints of s.
+func jsDoc(s *fit.Schema, indent string) string {
+	tags := lines(s.Description)
+	tag := func(name string, v any) {
+		tags = append(tags, fmt.Sprintf("@%v %v", name, v))
+	}
+	if s.Format != "" {
+		tag("format", s.Format)
+	}
+	if s.ContentEncoding != "" {
+		tag("contentEncoding", s.ContentEncoding)
+	}
+	if s.Pattern != "" {
+		tag("pattern", s.Pattern)
+	}
+	for _, v := range []struct {
+		name  string
+		value any
+	}{
+		{"minLength", s.MinLength},
+		{"maxLength", s.MaxLength},
+		{"minimum", s.Minimum},
+		{"maximum", s.Maximum},
+		{"exclusiveMinimum", s.ExclusiveMinimum},
+		{"exclusiveMaximum", s.ExclusiveMaximum},
+		{"minItems", s.MinItems},
+		{"maxItems", s.MaxItems},
+		{"minProperties", s.MinProperties},
+		{"maxProperties", s.MaxProperties},
+	} {
+		switch n := v.value.(type) {
+		case *int:
+			if n != nil {
+				tag(v.name, *n)
+			}
+		case *float64:
+			if n != nil {
+				tag(v.name, strconv.FormatFloat(*n, 'f', -1, 64))
+			}
+		}
+	}
+	for _, example := range s.Examples {
+		tag("example", literal(example))
+	}
+	return jsComment(tags, indent)
+}
+
+// jsComment is a /** */ comment of lines, or "" without any.
+func jsComment(lines []string, indent string) string {
+	switch len(lines) {
+	case 0:
+		return ""
+	case 1:
+		return indent + "/** " + lines[0] + " */\n"
+	default:
+		var b strings.Builder
+		b.WriteString(indent + "/**\n")
+		for _, line := range lines {
+			b.WriteString(strings.TrimRight(indent+" * "+line, " ") + "\n")
+		}
+		b.WriteString(indent + " */\n")
+		return b.String()
+	}
+}
+
+// lines splits a doc comment into lines, escaping anything that
+// would end a comment early.
+func lines(doc string) []string {
+	if doc == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(doc, "*/", "*\\/"), "\n")
+}
+
+func literal(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "unknown"
+	}
+	return string(data)
+}
+
+// propertyName quotes names that aren't valid identifiers.
+func propertyName(name string) string {
+	for i, r := range name {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// typeName is the go type name of a $defs name, e.g. users.User -> User
+func typeName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// pascalCase joins the parts of a dotted name, e.g. users.Service -> UsersService
+func pascalCase(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// lowerCamelCase lowers the leading initialism of a go name,
+// e.g. GetByUUID -> getByUUID, UUIDLookup -> uuidLookup
+func lowerCamelCase(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
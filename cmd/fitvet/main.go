@@ -0,0 +1,42 @@
+// Command fitvet reports the exported methods of fit services which
+// fit.NewRPC would skip for their signature, like fit.WithStrict does
+// when they start, but at build time.
+//
+//	go run ./cmd/fitvet ./...
+//	internal/server/run.go:42:22: NewRPC skips users.Service.Ping: in is string, want a struct or a pointer to one
+//
+// It exits with 1 when it reports anything.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hyqe/ribose/internal/fit/fitvet"
+)
+
+func main() {
+	log.SetFlags(0)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: fitvet [packages]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	fset, diags, err := fitvet.Vet(patterns...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, d := range diags {
+		fmt.Fprintf(os.Stderr, "%v: %v\n", fset.Position(d.Pos), d.Message)
+	}
+	if len(diags) > 0 {
+		os.Exit(1)
+	}
+}
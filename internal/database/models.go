@@ -6,11 +6,36 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type IdempotencyKey struct {
+	Principal     string
+	Method        string
+	Key           string
+	BatchItem     int32
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	RequestHash   []byte
+	Done          bool
+	StatusCode    int32
+	StatusMessage string
+	StatusDetails json.RawMessage
+	Output        json.RawMessage
+}
+
+type Password struct {
+	Uuid      uuid.UUID
+	CreatedAt time.Time
+	UserID    int64
+	Salt      string
+	Algorithm string
+	Hash      string
+}
+
 type User struct {
 	ID        sql.NullInt64
 	CreatedAt time.Time
@@ -0,0 +1,29 @@
+-- name: ClaimIdempotencyKey :execrows
+INSERT INTO idempotency_keys (
+    principal,
+    method,
+    key,
+    batch_item,
+    request_hash,
+    expires_at
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (principal, method, key, batch_item) DO UPDATE
+SET
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at,
+    request_hash = EXCLUDED.request_hash,
+    done = false,
+    status_code = 0,
+    status_message = '',
+    status_details = '[]',
+    output = 'null'
+WHERE
+    idempotency_keys.expires_at < now();
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET
+    done = true,
+    status_code = $5,
+    status_message = $6,
+    status_details = $7,
+    output = $8
+WHERE
+    principal = $1
+    AND method = $2
+    AND key = $3
+    AND batch_item = $4;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE
+FROM idempotency_keys
+WHERE
+    expires_at < now();
\ No newline at end of file
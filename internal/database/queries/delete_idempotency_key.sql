@@ -0,0 +1,8 @@
+-- name: DeleteIdempotencyKey :exec
+DELETE
+FROM idempotency_keys
+WHERE
+    principal = $1
+    AND method = $2
+    AND key = $3
+    AND batch_item = $4;
\ No newline at end of file
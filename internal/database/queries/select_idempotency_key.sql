@@ -0,0 +1,8 @@
+-- name: GetIdempotencyKey :one
+SELECT *
+FROM idempotency_keys
+WHERE
+    principal = $1
+    AND method = $2
+    AND key = $3
+    AND batch_item = $4;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    principal TEXT NOT NULL,
+    method TEXT NOT NULL,
+    key TEXT NOT NULL,
+    batch_item INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    request_hash BYTEA NOT NULL,
+    done BOOLEAN NOT NULL DEFAULT false,
+    status_code INT NOT NULL DEFAULT 0,
+    status_message TEXT NOT NULL DEFAULT '',
+    status_details JSONB NOT NULL DEFAULT '[]',
+    output JSONB NOT NULL DEFAULT 'null',
+    PRIMARY KEY (principal, method, key, batch_item)
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
\ No newline at end of file
@@ -0,0 +1,157 @@
+package fit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// DefaultMaxBatchSize is the most calls a batch may contain
+// unless changed with WithMaxBatchSize.
+const DefaultMaxBatchSize = 100
+
+// BatchCall is a single call of a batch.
+//
+//	POST /<type>/_batch
+//	[{"method": "GetByUUID", "input": {"uuid": "..."}}]
+type BatchCall struct {
+	Method string          `json:"method"`
+	Input  json.RawMessage `json:"input"`
+}
+
+// BatchResult is the result of the BatchCall at the same index.
+type BatchResult struct {
+	Output any         `json:"output,omitempty"`
+	Status BatchStatus `json:"status"`
+}
+
+type BatchStatus struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message,omitempty"`
+	Details []any      `json:"details,omitempty"`
+}
+
+// WithMaxBatchSize limits the number of calls in a batch. Larger
+// batches are rejected with codes.RequestEntityTooLarge. A limit
+// <= 0 allows any size.
+func WithMaxBatchSize(n int) Option {
+	return func(r *RPC) {
+		r.maxBatchSize = n
+	}
+}
+
+// WithBatchConcurrency runs up to n calls of a batch at once.
+// By default they run one after another.
+func WithBatchConcurrency(n int) Option {
+	return func(r *RPC) {
+		r.batchConcurrency = n
+	}
+}
+
+// batchCodec encodes batches, which are always JSON.
+func (s *RPC) batchCodec() Codec {
+	return s.codecs["application/json"]
+}
+
+// batch runs every call of body through the interceptors, as if
+// each was its own request.
+func (s *RPC) batch(ctx context.Context, body []byte) ([]BatchResult, status.Status) {
+	var calls []BatchCall
+	if err := s.batchCodec().Unmarshal(body, &calls); err != nil {
+		return nil, status.Newf(codes.BadRequest, "failed to decode body: %v", err)
+	}
+	if s.maxBatchSize > 0 && len(calls) > s.maxBatchSize {
+		return nil, status.Newf(codes.RequestEntityTooLarge, "batch of %v calls exceeds the limit of %v", len(calls), s.maxBatchSize)
+	}
+
+	concurrency := s.batchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	results := make([]BatchResult, len(calls))
+	var wg sync.WaitGroup
+	for i := range calls {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			out, st := s.batchCall(withBatchItem(ctx, i), calls[i])
+			results[i] = BatchResult{
+				Output: out,
+				Status: BatchStatus{
+					Code:    st.Code,
+					Message: st.Message,
+					Details: st.Details(),
+				},
+			}
+		}(i)
+	}
+	wg.Wait()
+	return results, status.OK
+}
+
+func (s *RPC) batchCall(ctx context.Context, call BatchCall) (any, status.Status) {
+	method, ok := s.methods[call.Method]
+	if !ok {
+		return nil, status.Newf(codes.NotFound, "method not found: %v", call.Method)
+	}
+	in := method.newIn()
+	if len(call.Input) > 0 {
+		if err := s.batchCodec().Unmarshal(call.Input, in); err != nil {
+			return nil, status.Newf(codes.BadRequest, "failed to decode input: %v", err)
+		}
+	}
+	out, st := s.call(ctx, method, in)
+	if st.Code >= 300 || st.Code == codes.NoContent {
+		return nil, st
+	}
+	return out, st
+}
+
+func (s *RPC) fiberBatch(c *fiber.Ctx) error {
+	ctx, md, cancel := fiberContext(c, TransportHTTP)
+	defer cancel()
+	results, st := s.batch(ctx, c.Body())
+	setFiberHeader(c, md)
+	if st.Code >= 300 {
+		return sendFiberError(c, st)
+	}
+	data, err := s.batchCodec().Marshal(results)
+	if err != nil {
+		return sendFiberError(c, status.Newf(codes.Internal, "failed to encode response: %v", err))
+	}
+	c.Set(fiber.HeaderContentType, s.batchCodec().ContentType())
+	return c.Send(data)
+}
+
+func (s *RPC) netHttpBatch(w http.ResponseWriter, r *http.Request) {
+	body, st := readHTTPBody(w, r)
+	if st.Code >= 300 {
+		writeHTTPError(w, r, st)
+		return
+	}
+	ctx, md := httpContext(w, r, TransportHTTP)
+	results, st := s.batch(ctx, body)
+	setHTTPHeader(w, md)
+	if st.Code >= 300 {
+		writeHTTPError(w, r, st)
+		return
+	}
+	data, err := s.batchCodec().Marshal(results)
+	if err != nil {
+		writeHTTPError(w, r, status.Newf(codes.Internal, "failed to encode response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", s.batchCodec().ContentType())
+	w.Write(data)
+}
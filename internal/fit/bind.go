@@ -0,0 +1,357 @@
+package fit
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// MethodOption configures a single method of an RPC.
+type MethodOption func(*Method)
+
+// WithMethod configures the method called name. It panics when
+// there is no such method, as that is a programming error.
+//
+//	fit.NewRPC(svc, fit.WithMethod("GetByUUID", fit.Get("/{uuid}")))
+func WithMethod(name string, opts ...MethodOption) Option {
+	return func(r *RPC) {
+		m, ok := r.methods[name]
+		if err, skipped := r.skipped[name]; skipped {
+			panic(fmt.Sprintf("fit: WithMethod: %v skips %v: %v", r.Name(), name, err))
+		}
+		if !ok {
+			panic(fmt.Sprintf("fit: WithMethod: %v has no method %v", r.Name(), name))
+		}
+		for _, opt := range opts {
+			opt(m)
+		}
+	}
+}
+
+// Get serves a read-only method on GET as well as POST, so its
+// responses can be cached and linked to. path is appended to the
+// path of the method, e.g. /users.Service/GetByUUID/{uuid}
+//
+// Instead of a body, INPUT is bound from the request by tags.
+// Fields without any are bound from the query by their json name.
+//
+//	type GetByUUIDRequest struct {
+//		UUID  uuid.UUID `json:"uuid" path:"uuid"`
+//		Token string    `json:"-" header:"Authorization"`
+//		Limit int       `json:"limit" query:"limit"`
+//	}
+//
+// Streaming methods served on GET can be read by an EventSource.
+//
+// It panics unless every {name} of path is a field of INPUT tagged
+// path:"name", and every such field is in path, as Register does
+// for bad signatures.
+func Get(path string) MethodOption {
+	return func(m *Method) {
+		m.get = true
+		m.getPath = strings.Trim(path, "/")
+		if err := checkGetPath(m.getPath, m.inType.Elem()); err != nil {
+			panic(fmt.Sprintf("fit: Get: %v: %v", m.name, err))
+		}
+	}
+}
+
+// checkGetPath checks that the {name} segments of path are the
+// path tags of the fields of in.
+func checkGetPath(path string, in reflect.Type) error {
+	var names []string
+	segs := make(map[string]bool)
+	if path != "" {
+		for _, seg := range strings.Split(path, "/") {
+			name, ok := pathParam(seg)
+			if !ok {
+				continue
+			}
+			if name == "" {
+				return fmt.Errorf("/%v has an empty segment {}", path)
+			}
+			if segs[name] {
+				return fmt.Errorf("/%v has {%v} twice", path, name)
+			}
+			names = append(names, name)
+			segs[name] = true
+		}
+	}
+	tags := make(map[string]bool)
+	for _, field := range bindFields(in) {
+		if field.in != "path" {
+			continue
+		}
+		if !segs[field.name] {
+			return fmt.Errorf("%v is tagged path:%q, but /%v has no {%v}", field.Name, field.name, path, field.name)
+		}
+		tags[field.name] = true
+	}
+	for _, name := range names {
+		if !tags[name] {
+			return fmt.Errorf("/%v has {%v}, but no field of %v is tagged path:%q", path, name, in, name)
+		}
+	}
+	return nil
+}
+
+func pathParam(seg string) (string, bool) {
+	if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+		return seg[1 : len(seg)-1], true
+	}
+	return "", false
+}
+
+// requestValues is where a GET request binds INPUT from.
+type requestValues struct {
+	path   func(name string) string
+	query  url.Values
+	header func(name string) string
+}
+
+// bindField is a field of INPUT bound from a GET request.
+type bindField struct {
+	reflect.StructField
+	index []int
+	// in is path, query or header.
+	in   string
+	name string
+}
+
+// bindFields lists the fields of t bound from a GET request,
+// including those promoted from embedded structs.
+func bindFields(t reflect.Type) []bindField {
+	var fields []bindField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if field.Anonymous && jsonName == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, promoted := range bindFields(ft) {
+					promoted.index = append([]int{i}, promoted.index...)
+					fields = append(fields, promoted)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		bf := bindField{StructField: field, index: []int{i}}
+		switch {
+		case field.Tag.Get("path") != "":
+			bf.in, bf.name = "path", field.Tag.Get("path")
+		case field.Tag.Get("header") != "":
+			bf.in, bf.name = "header", field.Tag.Get("header")
+		case field.Tag.Get("query") != "":
+			bf.in, bf.name = "query", field.Tag.Get("query")
+		case jsonName != "-":
+			bf.in, bf.name = "query", jsonName
+			if bf.name == "" {
+				bf.name = field.Name
+			}
+		default:
+			continue
+		}
+		fields = append(fields, bf)
+	}
+	return fields
+}
+
+// bind sets the fields of in, a *struct, from the request.
+func bind(in any, from requestValues) status.Status {
+	v := reflect.ValueOf(in).Elem()
+	var violations []status.FieldViolation
+	for _, field := range bindFields(v.Type()) {
+		var values []string
+		switch field.in {
+		case "path":
+			if s := from.path(field.name); s != "" {
+				values = []string{s}
+			}
+		case "header":
+			if s := from.header(field.name); s != "" {
+				values = []string{s}
+			}
+		case "query":
+			values = from.query[field.name]
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if err := setField(fieldByIndex(v, field.index), values); err != nil {
+			violations = append(violations, status.FieldViolation{
+				Field:       field.name,
+				Description: err.Error(),
+			})
+		}
+	}
+	if len(violations) > 0 {
+		return status.New(codes.BadRequest, "failed to bind request").WithDetails(status.BadRequest{FieldViolations: violations})
+	}
+	return status.OK
+}
+
+// fieldByIndex is v.FieldByIndex, allocating nil embedded pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// bindIn binds the INPUT of method from a GET request. Its
+// OUTPUT defaults to JSON.
+func (s *RPC) bindIn(method *Method, from requestValues) (any, Codec, status.Status) {
+	in := method.newIn()
+	if st := bind(in, from); st.Code >= 300 {
+		return nil, nil, st
+	}
+	return in, s.codecs["application/json"], status.OK
+}
+
+// fiberIn decodes the INPUT of method from the body, or binds it
+// on GET.
+func (s *RPC) fiberIn(c *fiber.Ctx, method *Method) (any, Codec, status.Status) {
+	if (c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead) && method.get {
+		query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+		if err != nil {
+			return nil, nil, status.Newf(codes.BadRequest, "invalid query: %v", err)
+		}
+		return s.bindIn(method, requestValues{
+			path: func(name string) string {
+				v, _ := url.PathUnescape(c.Params(name))
+				return v
+			},
+			query:  query,
+			header: func(name string) string { return c.Get(name) },
+		})
+	}
+	return s.decodeIn(method, c.Get(fiber.HeaderContentType), c.Body())
+}
+
+// netHttpIn decodes the INPUT of method from the body, or binds
+// it on GET from params, the {name} segments of its path.
+func (s *RPC) netHttpIn(w http.ResponseWriter, r *http.Request, method *Method, params map[string]string) (any, Codec, status.Status) {
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && method.get {
+		query, err := url.ParseQuery(r.URL.RawQuery)
+		if err != nil {
+			return nil, nil, status.Newf(codes.BadRequest, "invalid query: %v", err)
+		}
+		return s.bindIn(method, requestValues{
+			path:   func(name string) string { return params[name] },
+			query:  query,
+			header: r.Header.Get,
+		})
+	}
+	body, st := readHTTPBody(w, r)
+	if st.Code >= 300 {
+		return nil, nil, st
+	}
+	return s.decodeIn(method, r.Header.Get("Content-Type"), body)
+}
+
+// maxHTTPBodySize bounds the bodies read on net/http, like the
+// default BodyLimit of fiber.
+const maxHTTPBodySize = fiber.DefaultBodyLimit
+
+// readHTTPBody reads the body of r, failing with
+// codes.RequestEntityTooLarge past maxHTTPBodySize.
+func readHTTPBody(w http.ResponseWriter, r *http.Request) ([]byte, status.Status) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, status.Newf(codes.RequestEntityTooLarge, "body exceeds the limit of %v bytes", tooLarge.Limit)
+	}
+	if err != nil {
+		return nil, status.Newf(codes.BadRequest, "failed to read body: %v", err)
+	}
+	return body, status.OK
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setField parses values into v, which takes all of them when
+// it is a slice, and the first otherwise.
+func setField(v reflect.Value, values []string) error {
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0]))
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setField(elem.Elem(), values); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(values[0]))
+			return nil
+		}
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setField(s.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	value := values[0]
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a positive integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("can not be bound from %v", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package fit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+type bindService struct{}
+
+type bindByID struct {
+	ID    string `json:"id" path:"id"`
+	Limit int    `json:"limit"`
+}
+
+type bindEmbedded struct {
+	bindByID
+	Token string `json:"-" header:"Authorization"`
+}
+
+type bindNoPath struct {
+	Limit int `json:"limit"`
+}
+
+func (bindService) ByID(ctx context.Context, in *bindByID) status.Status { return status.OK }
+
+func (bindService) Embedded(ctx context.Context, in *bindEmbedded) status.Status { return status.OK }
+
+func (bindService) NoPath(ctx context.Context, in *bindNoPath) status.Status { return status.OK }
+
+func (bindService) NoInput(ctx context.Context) status.Status { return status.OK }
+
+func TestGetPath(t *testing.T) {
+	tests := []struct {
+		method, path string
+		// panic is part of the panic, or "" for none.
+		panic string
+	}{
+		{method: "ByID", path: "/{id}"},
+		{method: "ByID", path: "/by/{id}/"},
+		{method: "Embedded", path: "/{id}"},
+		{method: "NoPath", path: ""},
+		{method: "NoPath", path: "/all"},
+		{method: "NoInput", path: ""},
+
+		{method: "ByID", path: "", panic: `ID is tagged path:"id", but / has no {id}`},
+		{method: "ByID", path: "/{uuid}", panic: `ID is tagged path:"id", but /{uuid} has no {id}`},
+		{method: "Embedded", path: "", panic: `ID is tagged path:"id"`},
+		{method: "NoPath", path: "/{id}", panic: `/{id} has {id}, but no field of fit.bindNoPath is tagged path:"id"`},
+		{method: "NoInput", path: "/{id}", panic: `/{id} has {id}, but no field`},
+		{method: "ByID", path: "/{id}/{id}", panic: `/{id}/{id} has {id} twice`},
+		{method: "NoPath", path: "/{}", panic: `/{} has an empty segment {}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.path, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if tt.panic == "" {
+					if r != nil {
+						t.Fatalf("got panic %v, want none", r)
+					}
+					return
+				}
+				if msg := fmt.Sprint(r); !strings.HasPrefix(msg, "fit: Get: "+tt.method+": ") || !strings.Contains(msg, tt.panic) {
+					t.Fatalf("got panic %q, want one with %q", msg, tt.panic)
+				}
+			}()
+			NewRPC(bindService{}, WithMethod(tt.method, Get(tt.path)))
+		})
+	}
+}
+
+func TestGetPathRegister(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("got no panic for a path without {id}")
+		}
+	}()
+	Register(New("bind"), "ByID", func(ctx context.Context, in *bindByID) (*bindNoPath, status.Status) {
+		return nil, status.OK
+	}, Get("/all"))
+}
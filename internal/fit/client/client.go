@@ -0,0 +1,350 @@
+// Package client calls fit services over HTTP.
+//
+// A typed client is a struct of funcs named after the methods of
+// the service, with the same signature minus the receiver.
+//
+//	type UsersClient struct {
+//		Create    func(context.Context, *users.CreateRequest) (*users.CreateResponse, status.Status)
+//		GetByUUID func(context.Context, *users.GetByUUIDRequest) (*users.GetByUUIDResponse, status.Status)
+//	}
+//
+//	var uc UsersClient
+//	c := client.New("http://localhost", client.WithRetries(3, 100*time.Millisecond))
+//	err := c.Bind((*users.Service)(nil), &uc)
+//	...
+//	u, s := uc.Create(ctx, &users.CreateRequest{Email: "foo@example.com"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyqe/ribose/internal/fit"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests with hc instead of a new http.Client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithHeader adds a header to every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithTimeout limits the time of each attempt of a call.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRetries retries a call up to n times when the server could
+// not be reached, or responded 429, 502, 503 or 504. The server's
+// Retry-After header is honored, otherwise attempts back off
+// exponentially starting from backoff.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// New builds a Client for the fit services served at baseURL.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{},
+		header:     make(http.Header),
+		backoff:    100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Call POSTs in to /<service>/<method> and decodes the response into out.
+func (c *Client) Call(ctx context.Context, service, method string, in, out any) status.Status {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return status.Newf(codes.BadRequest, "failed to encode body: %v", err)
+	}
+	endpoint, err := url.JoinPath(c.baseURL, service, method)
+	if err != nil {
+		return status.Newf(codes.BadRequest, "invalid url: %v", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		s, retryAfter, retry := c.try(ctx, endpoint, body, out)
+		if !retry || attempt >= c.retries || ctx.Err() != nil {
+			return s
+		}
+		if retryAfter <= 0 {
+			retryAfter = c.backoff << attempt
+		}
+		if err := sleep(ctx, retryAfter); err != nil {
+			return transportStatus(err)
+		}
+	}
+}
+
+// try makes a single attempt of a call, reporting whether it
+// can be retried, and how long the server asked to wait.
+func (c *Client) try(ctx context.Context, endpoint string, body []byte, out any) (s status.Status, wait time.Duration, retry bool) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return status.Newf(codes.BadRequest, "failed to build request: %v", err), 0, false
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	// let the server give up when the caller would.
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining > 0 {
+			req.Header.Set(fit.RequestTimeoutHeader, strconv.FormatFloat(remaining.Seconds(), 'f', 3, 64))
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return transportStatus(err), 0, true
+	}
+	defer resp.Body.Close()
+
+	s = decodeResponse(resp, out)
+	if retryable(resp.StatusCode) {
+		if info, ok := status.DetailOf[status.RetryInfo](s); ok {
+			wait = info.RetryDelay
+		} else {
+			wait, _ = retryAfter(resp.Header.Get("Retry-After"))
+		}
+		return s, wait, true
+	}
+	return s, 0, false
+}
+
+// decodeResponse decodes a 2xx body into out, and anything
+// else into the returned status.Status.
+func decodeResponse(resp *http.Response, out any) status.Status {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return status.Newf(codes.BadGateway, "failed to read response: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		return errorStatus(resp.StatusCode, data)
+	}
+	if len(data) > 0 && out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return status.Newf(codes.BadGateway, "failed to decode response: %v", err)
+		}
+	}
+	return status.Status{Code: codes.Code(resp.StatusCode)}
+}
+
+// errorStatus decodes a fit.ErrorBody, falling back to the
+// body as text when it isn't one, e.g. from a proxy.
+func errorStatus(code int, data []byte) status.Status {
+	var body struct {
+		fit.ErrorBody
+		Details []json.RawMessage `json:"details"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Code != 0 {
+		s := status.New(codes.Code(code), body.Message)
+		for _, raw := range body.Details {
+			detail, err := status.DecodeDetail(raw)
+			if err != nil {
+				detail = raw
+			}
+			s = s.WithDetails(detail)
+		}
+		return s
+	}
+	message := strings.TrimSpace(string(data))
+	if message == "" {
+		message = http.StatusText(code)
+	}
+	return status.New(codes.Code(code), message)
+}
+
+func retryable(code int) bool {
+	switch codes.Code(code) {
+	case codes.TooManyRequests, codes.BadGateway, codes.ServiceUnavailable, codes.GatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After header, which is either
+// seconds or an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// transportStatus converts an error sending a request into a Status.
+func transportStatus(err error) status.Status {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.GatewayTimeout, err)
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.RequestTimeout, err)
+	default:
+		return status.New(codes.ServiceUnavailable, err)
+	}
+}
+
+// Unary builds a typed func calling method on service.
+//
+//	create := client.Unary[users.CreateRequest, users.CreateResponse](c, "users.Service", "Create")
+func Unary[I, O any](c *Client, service, method string) func(context.Context, *I) (*O, status.Status) {
+	return func(ctx context.Context, in *I) (*O, status.Status) {
+		out := new(O)
+		s := c.Call(ctx, service, method, in, out)
+		if s.Code >= 300 {
+			return nil, s
+		}
+		return out, s
+	}
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	statusType  = reflect.TypeOf(status.Status{})
+)
+
+// Bind fills the func fields of stub, a pointer to a struct, with
+// calls to the methods of the same name on the service svc is an
+// instance of. svc can be a nil pointer.
+//
+// Each field must have the signature of its method without the
+// receiver. Fields that aren't funcs are left alone.
+func (c *Client) Bind(svc any, stub any) error {
+	return c.BindNamed(fit.ServiceName(svc), svc, stub)
+}
+
+// BindNamed is Bind for a service served under another name, such
+// as when svc is a pointer to an interface of the service.
+//
+//	c.BindNamed("users.Service", (*UsersAPI)(nil), &uc)
+func (c *Client) BindNamed(service string, svc any, stub any) error {
+	svcType := reflect.TypeOf(svc)
+	// interface methods have no receiver.
+	receivers := 1
+	if svcType.Kind() == reflect.Pointer && svcType.Elem().Kind() == reflect.Interface {
+		svcType = svcType.Elem()
+		receivers = 0
+	}
+
+	v := reflect.ValueOf(stub)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("stub must be a pointer to a struct, got %T", stub)
+	}
+	v = v.Elem()
+
+	for f := 0; f < v.NumField(); f++ {
+		field := v.Type().Field(f)
+		if !field.IsExported() || field.Type.Kind() != reflect.Func {
+			continue
+		}
+		method, ok := svcType.MethodByName(field.Name)
+		if !ok {
+			return fmt.Errorf("%v has no method %v", service, field.Name)
+		}
+		if err := checkSignature(field.Type, method.Type, receivers); err != nil {
+			return fmt.Errorf("%v.%v: %w", service, field.Name, err)
+		}
+		v.Field(f).Set(c.makeFunc(field.Type, service, field.Name))
+	}
+	return nil
+}
+
+// checkSignature ensures fn is the signature of method without its receiver,
+//
+//	func(context.Context, *I) (*O, status.Status)
+func checkSignature(fn, method reflect.Type, receivers int) error {
+	if fn.NumIn() != 2 || fn.NumOut() != 2 ||
+		fn.In(0) != contextType ||
+		fn.In(1).Kind() != reflect.Pointer ||
+		fn.Out(0).Kind() != reflect.Pointer ||
+		fn.Out(1) != statusType {
+		return fmt.Errorf("want func(context.Context, *I) (*O, status.Status), got %v", fn)
+	}
+	if method.NumIn() != 2+receivers || method.NumOut() != 2 ||
+		method.In(1+receivers) != fn.In(1) || method.Out(0) != fn.Out(0) {
+		return fmt.Errorf("%v does not match %v", fn, method)
+	}
+	return nil
+}
+
+func (c *Client) makeFunc(fn reflect.Type, service, method string) reflect.Value {
+	outType := fn.Out(0).Elem()
+	return reflect.MakeFunc(fn, func(args []reflect.Value) []reflect.Value {
+		ctx, _ := args[0].Interface().(context.Context)
+		out := reflect.New(outType)
+		s := c.Call(ctx, service, method, args[1].Interface(), out.Interface())
+		if s.Code >= 300 {
+			out = reflect.Zero(fn.Out(0))
+		}
+		return []reflect.Value{out, reflect.ValueOf(s)}
+	})
+}
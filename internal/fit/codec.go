@@ -0,0 +1,215 @@
+package fit
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Codec encodes the INPUT and OUTPUT of methods. The codec of
+// a request is picked by its Content-Type, and the codec of the
+// response by its Accept header, defaulting to that of the request.
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// WithCodecs registers codecs by their content type, replacing
+// any already registered for it. JSON, MessagePack and CBOR are
+// registered by default. A faster JSON engine can be
+// swapped in with a JSONCodec.
+//
+//	fit.WithCodecs(fit.JSONCodec{MarshalFunc: sonic.Marshal, UnmarshalFunc: sonic.Unmarshal})
+func WithCodecs(codecs ...Codec) Option {
+	return func(r *RPC) {
+		for _, codec := range codecs {
+			r.codecs[codec.ContentType()] = codec
+		}
+	}
+}
+
+func defaultCodecs() map[string]Codec {
+	return map[string]Codec{
+		"application/json":        JSONCodec{},
+		"application/msgpack":     MsgPackCodec{},
+		"application/x-msgpack":   MsgPackCodec{},
+		"application/vnd.msgpack": MsgPackCodec{},
+		"application/cbor":        CBORCodec{},
+	}
+}
+
+// negotiate picks the codecs to decode a request and encode
+// its response with.
+func (s *RPC) negotiate(contentType, accept string) (in, out Codec, st status.Status) {
+	in = s.codecs["application/json"]
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, nil, status.Newf(codes.UnsupportedMediaType, "invalid Content-Type: %v", err)
+		}
+		codec, ok := s.codecs[mediaType]
+		if !ok {
+			return nil, nil, status.Newf(codes.UnsupportedMediaType, "unsupported Content-Type: %v", mediaType)
+		}
+		in = codec
+	}
+
+	out, ok := s.acceptable(accept, in)
+	if !ok {
+		return nil, nil, status.Newf(codes.NotAcceptable, "none of the accepted types are supported: %v", accept)
+	}
+	return in, out, status.OK
+}
+
+// acceptable picks the most preferred codec of an Accept header,
+// using fallback for wildcards.
+func (s *RPC) acceptable(accept string, fallback Codec) (Codec, bool) {
+	if accept == "" {
+		return fallback, true
+	}
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	for _, r := range ranges {
+		if strings.HasSuffix(r.mediaType, "/*") {
+			return fallback, true
+		}
+		if codec, ok := s.codecs[r.mediaType]; ok {
+			return codec, true
+		}
+	}
+	return nil, false
+}
+
+// JSONCodec encodes application/json with encoding/json, unless
+// MarshalFunc and UnmarshalFunc are set.
+type JSONCodec struct {
+	MarshalFunc   func(v any) ([]byte, error)
+	UnmarshalFunc func(data []byte, v any) error
+}
+
+func (JSONCodec) ContentType() string { return "application/json" }
+
+func (c JSONCodec) Marshal(v any) ([]byte, error) {
+	if c.MarshalFunc != nil {
+		return c.MarshalFunc(v)
+	}
+	return json.Marshal(v)
+}
+
+func (c JSONCodec) Unmarshal(data []byte, v any) error {
+	if c.UnmarshalFunc != nil {
+		return c.UnmarshalFunc(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// MsgPackCodec encodes application/msgpack. Types with msgp
+// generated methods are encoded with them, others are bridged
+// through their json encoding so their json tags are honored.
+type MsgPackCodec struct{}
+
+func (MsgPackCodec) ContentType() string { return "application/msgpack" }
+
+func (MsgPackCodec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(msgp.Marshaler); ok {
+		return m.MarshalMsg(nil)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return msgp.AppendIntf(nil, msgpackNumbers(generic))
+}
+
+func (MsgPackCodec) Unmarshal(data []byte, v any) error {
+	if u, ok := v.(msgp.Unmarshaler); ok {
+		_, err := u.UnmarshalMsg(data)
+		return err
+	}
+	var buf bytes.Buffer
+	if _, err := msgp.UnmarshalAsJSON(&buf, data); err != nil {
+		return err
+	}
+	return json.Unmarshal(buf.Bytes(), v)
+}
+
+// msgpackNumbers converts json.Numbers into the narrowest msgpack
+// number that holds them.
+func msgpackNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = msgpackNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = msgpackNumbers(e)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// CBORCodec encodes application/cbor. Struct fields fall back
+// to their json tags.
+type CBORCodec struct{}
+
+var (
+	cborEnc, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	cborDec, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil))}.DecMode()
+)
+
+func (CBORCodec) ContentType() string { return "application/cbor" }
+
+func (CBORCodec) Marshal(v any) ([]byte, error) {
+	return cborEnc.Marshal(v)
+}
+
+func (CBORCodec) Unmarshal(data []byte, v any) error {
+	return cborDec.Unmarshal(data, v)
+}
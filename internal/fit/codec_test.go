@@ -0,0 +1,85 @@
+package fit
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type codecInner struct {
+	Name string `json:"name"`
+}
+
+type codecValue struct {
+	UUID     uuid.UUID          `json:"uuid"`
+	Time     time.Time          `json:"time"`
+	Optional *string            `json:"optional"`
+	Missing  *string            `json:"missing,omitempty"`
+	Int      int64              `json:"int"`
+	Uint     uint64             `json:"uint"`
+	Float    float64            `json:"float"`
+	Bool     bool               `json:"bool"`
+	Bytes    []byte             `json:"bytes"`
+	Ints     []int              `json:"ints"`
+	Scores   map[string]float64 `json:"scores"`
+	Inner    codecInner         `json:"inner"`
+	Inners   []*codecInner      `json:"inners"`
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	optional := "here"
+	want := codecValue{
+		UUID:     uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		Time:     time.Date(2023, 6, 1, 12, 30, 45, 123456789, time.UTC),
+		Optional: &optional,
+		Int:      math.MinInt64,
+		Uint:     math.MaxUint64,
+		Float:    1.5,
+		Bool:     true,
+		Bytes:    []byte{0, 1, 2, 255},
+		Ints:     []int{1, -2, 3},
+		Scores:   map[string]float64{"a": 0.25, "b": 2},
+		Inner:    codecInner{Name: "inner"},
+		Inners:   []*codecInner{{Name: "a"}, {Name: "b"}},
+	}
+	for contentType, codec := range defaultCodecs() {
+		t.Run(contentType, func(t *testing.T) {
+			data, err := codec.Marshal(want)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			var got codecValue
+			if err := codec.Unmarshal(data, &got); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got  %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCodecTimeZone(t *testing.T) {
+	want := time.Date(2023, 6, 1, 12, 30, 45, 0, time.FixedZone("", -7*60*60))
+	for contentType, codec := range defaultCodecs() {
+		t.Run(contentType, func(t *testing.T) {
+			data, err := codec.Marshal(codecValue{Time: want})
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			var got codecValue
+			if err := codec.Unmarshal(data, &got); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if !got.Time.Equal(want) {
+				t.Errorf("got %v, want %v", got.Time, want)
+			}
+			if _, offset := got.Time.Zone(); offset != -7*60*60 {
+				t.Errorf("got offset %v, want -7h", offset)
+			}
+		})
+	}
+}
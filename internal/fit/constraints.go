@@ -0,0 +1,242 @@
+package fit
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// validateFormats are validator tags that map onto a JSON Schema format.
+var validateFormats = map[string]string{
+	"email":            "email",
+	"uuid":             "uuid",
+	"uuid3":            "uuid",
+	"uuid4":            "uuid",
+	"uuid5":            "uuid",
+	"uuid_rfc4122":     "uuid",
+	"uuid3_rfc4122":    "uuid",
+	"uuid4_rfc4122":    "uuid",
+	"uuid5_rfc4122":    "uuid",
+	"url":              "uri",
+	"http_url":         "uri",
+	"uri":              "uri",
+	"hostname":         "hostname",
+	"hostname_rfc1123": "hostname",
+	"fqdn":             "hostname",
+	"ipv4":             "ipv4",
+	"ipv6":             "ipv6",
+}
+
+// validatePatterns are validator tags that map onto a regular expression.
+var validatePatterns = map[string]string{
+	"alpha":       `^[a-zA-Z]+$`,
+	"alphanum":    `^[a-zA-Z0-9]+$`,
+	"numeric":     `^[-+]?[0-9]+(?:\.[0-9]+)?$`,
+	"number":      `^[0-9]+$`,
+	"hexadecimal": `^(0[xX])?[0-9a-fA-F]+$`,
+	"lowercase":   `^[^A-Z]*$`,
+	"uppercase":   `^[^a-z]*$`,
+	"e164":        `^\+[1-9]?[0-9]{7,14}$`,
+}
+
+// applyValidateTag translates the rules of a go-playground validate
+// tag into JSON Schema keywords on s, so clients see the same
+// constraints RPC.Validate.Struct enforces. Rules that have no JSON
+// Schema equivalent are skipped.
+//
+// It reports whether the tag requires the field, and whether the
+// tag marked it omitempty.
+func applyValidateTag(s *Schema, tag string) (required, omitEmpty bool) {
+	return applyValidateRules(s, splitValidateTag(tag))
+}
+
+func applyValidateRules(s *Schema, rules []string) (required, omitEmpty bool) {
+	for i := 0; i < len(rules); i++ {
+		rule := rules[i]
+		switch rule {
+		case "required":
+			required = true
+			continue
+		case "omitempty":
+			omitEmpty = true
+			continue
+		case "keys":
+			// key rules run until endkeys and have no
+			// equivalent on additionalProperties.
+			for i < len(rules) && rules[i] != "endkeys" {
+				i++
+			}
+			continue
+		case "dive":
+			elem := s.Items
+			if elem == nil {
+				elem = s.AdditionalProperties
+			}
+			if elem != nil && elem.Ref == "" && elem.AnyOf == nil {
+				applyValidateRules(elem, rules[i+1:])
+			}
+			return required, omitEmpty
+		}
+		if s.Ref != "" || s.AnyOf != nil {
+			continue
+		}
+		name, param, _ := strings.Cut(rule, "=")
+		applyValidateRule(s, name, param)
+	}
+	return required, omitEmpty
+}
+
+func applyValidateRule(s *Schema, name, param string) {
+	if format, ok := validateFormats[name]; ok {
+		s.Format = format
+		return
+	}
+	if pattern, ok := validatePatterns[name]; ok {
+		s.Pattern = pattern
+		return
+	}
+
+	switch name {
+	case "datetime":
+		switch param {
+		case "2006-01-02T15:04:05Z07:00":
+			s.Format = "date-time"
+		case "2006-01-02":
+			s.Format = "date"
+		case "15:04:05":
+			s.Format = "time"
+		}
+	case "startswith":
+		s.Pattern = "^" + regexp.QuoteMeta(param)
+	case "endswith":
+		s.Pattern = regexp.QuoteMeta(param) + "$"
+	case "contains":
+		s.Pattern = regexp.QuoteMeta(param)
+	case "oneof":
+		s.Enum = nil
+		for _, v := range splitOneOf(param) {
+			s.Enum = append(s.Enum, enumValue(s.Type, v))
+		}
+	case "eq":
+		s.Const = enumValue(s.Type, param)
+	case "len":
+		setLowerBound(s, param, false)
+		setUpperBound(s, param, false)
+	case "min", "gte":
+		setLowerBound(s, param, false)
+	case "gt":
+		setLowerBound(s, param, true)
+	case "max", "lte":
+		setUpperBound(s, param, false)
+	case "lt":
+		setUpperBound(s, param, true)
+	}
+}
+
+// setLowerBound sets the keyword the validator checks a min/gte/gt
+// param against for the kind of s.
+func setLowerBound(s *Schema, param string, exclusive bool) {
+	switch {
+	case s.Type.Is("integer"), s.Type.Is("number"):
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		if exclusive {
+			s.ExclusiveMinimum = &n
+		} else {
+			s.Minimum = &n
+		}
+	default:
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		if exclusive {
+			n++
+		}
+		switch {
+		case s.Type.Is("string"):
+			s.MinLength = &n
+		case s.Type.Is("array"):
+			s.MinItems = &n
+		case s.Type.Is("object"):
+			s.MinProperties = &n
+		}
+	}
+}
+
+// setUpperBound is the max/lte/lt counterpart of setLowerBound.
+func setUpperBound(s *Schema, param string, exclusive bool) {
+	switch {
+	case s.Type.Is("integer"), s.Type.Is("number"):
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		if exclusive {
+			s.ExclusiveMaximum = &n
+		} else {
+			s.Maximum = &n
+		}
+	default:
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		if exclusive {
+			n--
+		}
+		switch {
+		case s.Type.Is("string"):
+			s.MaxLength = &n
+		case s.Type.Is("array"):
+			s.MaxItems = &n
+		case s.Type.Is("object"):
+			s.MaxProperties = &n
+		}
+	}
+}
+
+// enumValue converts a validator param into a value of the schema type.
+func enumValue(typ SchemaType, v string) any {
+	switch {
+	case typ.Is("integer"), typ.Is("number"):
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case typ.Is("boolean"):
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// splitValidateTag splits a validate tag into its rules, restoring
+// the escaped commas and pipes the validator allows in params.
+// Rules combined with | are dropped since any one of them may hold.
+func splitValidateTag(tag string) []string {
+	var rules []string
+	for _, rule := range strings.Split(tag, ",") {
+		if rule == "" || rule == "-" || strings.Contains(rule, "|") {
+			continue
+		}
+		rules = append(rules, strings.NewReplacer("0x2C", ",", "0x7C", "|").Replace(rule))
+	}
+	return rules
+}
+
+var oneOfRegexp = regexp.MustCompile(`'[^']*'|\S+`)
+
+// splitOneOf splits a oneof param on spaces, keeping single
+// quoted values together.
+//
+//	oneof=red 'light blue' green
+func splitOneOf(param string) []string {
+	values := oneOfRegexp.FindAllString(param, -1)
+	for i, v := range values {
+		values[i] = strings.Trim(v, "'")
+	}
+	return values
+}
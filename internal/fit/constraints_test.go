@@ -0,0 +1,103 @@
+package fit
+
+import (
+	"testing"
+)
+
+func TestApplyValidateTag(t *testing.T) {
+	var (
+		str     = func() *Schema { return &Schema{Type: SchemaType{"string"}} }
+		integer = func() *Schema { return &Schema{Type: SchemaType{"integer"}} }
+		number  = func() *Schema { return &Schema{Type: SchemaType{"number"}} }
+		boolean = func() *Schema { return &Schema{Type: SchemaType{"boolean"}} }
+		array   = func() *Schema { return &Schema{Type: SchemaType{"array"}, Items: str()} }
+		object  = func() *Schema { return &Schema{Type: SchemaType{"object"}, AdditionalProperties: integer()} }
+		ref     = func() *Schema { return &Schema{Ref: "#/$defs/users.User"} }
+	)
+	tests := []struct {
+		tag       string
+		schema    *Schema
+		want      string
+		required  bool
+		omitEmpty bool
+	}{
+		{tag: "required", schema: str(), want: `{"type":"string"}`, required: true},
+		{tag: "omitempty,min=1", schema: str(), want: `{"type":"string","minLength":1}`, omitEmpty: true},
+
+		// formats
+		{tag: "email", schema: str(), want: `{"type":"string","format":"email"}`},
+		{tag: "uuid4", schema: str(), want: `{"type":"string","format":"uuid"}`},
+		{tag: "url", schema: str(), want: `{"type":"string","format":"uri"}`},
+		{tag: "fqdn", schema: str(), want: `{"type":"string","format":"hostname"}`},
+		{tag: "ipv4", schema: str(), want: `{"type":"string","format":"ipv4"}`},
+		{tag: "ipv6", schema: str(), want: `{"type":"string","format":"ipv6"}`},
+		{tag: "datetime=2006-01-02T15:04:05Z07:00", schema: str(), want: `{"type":"string","format":"date-time"}`},
+		{tag: "datetime=2006-01-02", schema: str(), want: `{"type":"string","format":"date"}`},
+		{tag: "datetime=15:04:05", schema: str(), want: `{"type":"string","format":"time"}`},
+
+		// patterns
+		{tag: "alpha", schema: str(), want: `{"type":"string","pattern":"^[a-zA-Z]+$"}`},
+		{tag: "alphanum", schema: str(), want: `{"type":"string","pattern":"^[a-zA-Z0-9]+$"}`},
+		{tag: "number", schema: str(), want: `{"type":"string","pattern":"^[0-9]+$"}`},
+		{tag: "e164", schema: str(), want: `{"type":"string","pattern":"^\\+[1-9]?[0-9]{7,14}$"}`},
+		{tag: "startswith=a.b", schema: str(), want: `{"type":"string","pattern":"^a\\.b"}`},
+		{tag: "endswith=.com", schema: str(), want: `{"type":"string","pattern":"\\.com$"}`},
+		{tag: "contains=0x2C", schema: str(), want: `{"type":"string","pattern":","}`},
+
+		// enums
+		{tag: "oneof=red 'light blue' green", schema: str(), want: `{"type":"string","enum":["red","light blue","green"]}`},
+		{tag: "oneof=1 2 3", schema: integer(), want: `{"type":"integer","enum":[1,2,3]}`},
+		{tag: "eq=true", schema: boolean(), want: `{"type":"boolean","const":true}`},
+		{tag: "eq=admin", schema: str(), want: `{"type":"string","const":"admin"}`},
+
+		// bounds
+		{tag: "min=1,max=10", schema: integer(), want: `{"type":"integer","minimum":1,"maximum":10}`},
+		{tag: "gte=0.5,lte=1.5", schema: number(), want: `{"type":"number","minimum":0.5,"maximum":1.5}`},
+		{tag: "gt=0,lt=1", schema: number(), want: `{"type":"number","exclusiveMinimum":0,"exclusiveMaximum":1}`},
+		{tag: "min=3,max=64", schema: str(), want: `{"type":"string","minLength":3,"maxLength":64}`},
+		{tag: "gt=3,lt=64", schema: str(), want: `{"type":"string","minLength":4,"maxLength":63}`},
+		{tag: "len=2", schema: str(), want: `{"type":"string","minLength":2,"maxLength":2}`},
+		{tag: "min=1,max=5", schema: array(), want: `{"type":"array","items":{"type":"string"},"minItems":1,"maxItems":5}`},
+		{tag: "min=1,max=5", schema: object(), want: `{"type":"object","additionalProperties":{"type":"integer"},"minProperties":1,"maxProperties":5}`},
+
+		// elements
+		{tag: "min=1,dive,email", schema: array(), want: `{"type":"array","items":{"type":"string","format":"email"},"minItems":1}`},
+		{tag: "dive,keys,alpha,endkeys,min=1", schema: object(), want: `{"type":"object","additionalProperties":{"type":"integer","minimum":1}}`},
+		{tag: "required,dive,min=1", schema: &Schema{Type: SchemaType{"array"}, Items: ref()}, want: `{"type":"array","items":{"$ref":"#/$defs/users.User"}}`, required: true},
+
+		// unsupported
+		{tag: "alphaunicode", schema: str(), want: `{"type":"string"}`},
+		{tag: "required_if=Kind admin", schema: str(), want: `{"type":"string"}`},
+		{tag: "min=1|max=0", schema: str(), want: `{"type":"string"}`},
+		{tag: "min=a,max=b", schema: integer(), want: `{"type":"integer"}`},
+		{tag: "datetime=Jan 2", schema: str(), want: `{"type":"string"}`},
+		{tag: "-", schema: str(), want: `{"type":"string"}`},
+		{tag: "required,min=1", schema: ref(), want: `{"$ref":"#/$defs/users.User"}`, required: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			required, omitEmpty := applyValidateTag(tt.schema, tt.tag)
+			if required != tt.required || omitEmpty != tt.omitEmpty {
+				t.Errorf("got required %v and omitempty %v, want %v and %v", required, omitEmpty, tt.required, tt.omitEmpty)
+			}
+			assertJSON(t, tt.schema, tt.want)
+		})
+	}
+}
+
+func TestApplyValidateTagEveryFormatAndPattern(t *testing.T) {
+	for tag, format := range validateFormats {
+		s := &Schema{Type: SchemaType{"string"}}
+		applyValidateTag(s, tag)
+		if s.Format != format {
+			t.Errorf("%v: got format %q, want %q", tag, s.Format, format)
+		}
+	}
+	for tag, pattern := range validatePatterns {
+		s := &Schema{Type: SchemaType{"string"}}
+		applyValidateTag(s, tag)
+		if s.Pattern != pattern {
+			t.Errorf("%v: got pattern %q, want %q", tag, s.Pattern, pattern)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package fit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// RequestTimeoutHeader lets a client shorten the deadline of a
+// call, in seconds or as a duration, e.g. 2.5 or 2500ms.
+const RequestTimeoutHeader = "Request-Timeout"
+
+// WithDefaultTimeout is the deadline of unary methods without a
+// Timeout of their own. Streaming methods have none by default.
+//
+// A call past its deadline fails with codes.GatewayTimeout, and
+// one canceled with codes.RequestTimeout. Calls are canceled when
+// their client goes away on net/http, but only when the server
+// shuts down on fiber, which doesn't tell. Streams are canceled on
+// both once writing to the client fails.
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(r *RPC) {
+		r.defaultTimeout = d
+	}
+}
+
+// Timeout is the deadline of a method, overriding the default.
+//
+//	fit.WithMethod("Export", fit.Timeout(time.Minute))
+func Timeout(d time.Duration) MethodOption {
+	return func(m *Method) {
+		m.timeout = d
+	}
+}
+
+// maxRequestTimeout is the longest Request-Timeout, in seconds,
+// which fits a time.Duration.
+const maxRequestTimeout = math.MaxInt64 / time.Second
+
+// withDeadline bounds ctx by the timeout of method, or the
+// Request-Timeout of the call when it is shorter. The header can't
+// lengthen or remove the timeout.
+func (s *RPC) withDeadline(ctx context.Context, method *Method) (context.Context, context.CancelFunc, status.Status) {
+	timeout := method.timeout
+	if timeout == 0 && method.stream == unary {
+		timeout = s.defaultTimeout
+	}
+	if v := MetadataFromContext(ctx).Header.Get(RequestTimeoutHeader); v != "" {
+		requested, err := parseRequestTimeout(v)
+		if err != nil {
+			return ctx, func() {}, status.Newf(codes.BadRequest, "invalid %v: %v", RequestTimeoutHeader, err)
+		}
+		if timeout == 0 || requested < timeout {
+			timeout = requested
+		}
+	}
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, status.OK
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, status.OK
+}
+
+func parseRequestTimeout(v string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+		if math.IsNaN(seconds) || seconds <= 0 {
+			return 0, errors.New("must be positive")
+		}
+		if seconds > float64(maxRequestTimeout) {
+			return 0, fmt.Errorf("must be at most %v seconds", int64(maxRequestTimeout))
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
+
+// contextStatus is the status of a call whose ctx is done, e.g.
+// codes.GatewayTimeout when its deadline passed, and
+// codes.RequestTimeout when the client went away.
+func contextStatus(err error) status.Status {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Newf(codes.GatewayTimeout, "deadline exceeded: %v", err)
+	}
+	return status.Newf(codes.RequestTimeout, "canceled: %v", err)
+}
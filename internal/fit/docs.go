@@ -0,0 +1,98 @@
+package fit
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// docs are the doc comments given to RegisterDocs, by package
+// path and then by name.
+var docs = struct {
+	sync.RWMutex
+	pkgs map[string]map[string]string
+}{pkgs: make(map[string]map[string]string)}
+
+// RegisterDocs adds the doc comments of the declarations of the
+// package at pkgPath, by name, e.g. Service, Service.Create or
+// User.Email. They describe services, methods, types and fields
+// in /help, OpenAPI and the clients generated from them.
+//
+// It is called by the code cmd/fitdoc generates.
+//
+//	//go:generate go run github.com/hyqe/ribose/cmd/fitdoc
+func RegisterDocs(pkgPath string, comments map[string]string) {
+	docs.Lock()
+	defer docs.Unlock()
+	pkg, ok := docs.pkgs[pkgPath]
+	if !ok {
+		pkg = make(map[string]string, len(comments))
+		docs.pkgs[pkgPath] = pkg
+	}
+	for name, doc := range comments {
+		pkg[name] = doc
+	}
+}
+
+// docKey is a declaration of a package, e.g. Service.Create
+type docKey struct {
+	pkgPath string
+	name    string
+}
+
+// doc is the doc comment of k, or "" without one.
+func (k docKey) doc() string {
+	if k.name == "" {
+		return ""
+	}
+	docs.RLock()
+	defer docs.RUnlock()
+	return docs.pkgs[k.pkgPath][k.name]
+}
+
+// typeDocKey is the docKey of the named type t, or of its member
+// when it isn't "".
+func typeDocKey(t reflect.Type, member string) docKey {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return docKey{}
+	}
+	k := docKey{pkgPath: t.PkgPath(), name: t.Name()}
+	if member != "" {
+		k.name += "." + member
+	}
+	return k
+}
+
+// funcDocKey is the docKey of fn, a func or method value, from
+// the name the runtime gives it, e.g.
+// github.com/hyqe/ribose/internal/users.(*Service).Create-fm
+func funcDocKey(fn any) docKey {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return docKey{}
+	}
+	name := f.Name()
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return docKey{}
+	}
+	pkgPath, name := name[:slash+1+dot], name[slash+1+dot+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	name = strings.NewReplacer("(*", "", "(", "", ")", "").Replace(name)
+	return docKey{pkgPath: pkgPath, name: name}
+}
+
+// summary is the first sentence of doc.
+func summary(doc string) string {
+	doc, _, _ = strings.Cut(doc, "\n\n")
+	doc = strings.Join(strings.Fields(doc), " ")
+	if i := strings.Index(doc, ". "); i >= 0 {
+		return doc[:i+1]
+	}
+	return doc
+}
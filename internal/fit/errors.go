@@ -0,0 +1,159 @@
+package fit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// ErrorBody is the JSON body written by every transport for a
+// status.Status with a code >= 300.
+//
+//	{
+//		"code": 400,
+//		"message": "validation failed",
+//		"details": [{
+//			"@type": "BadRequest",
+//			"field_violations": [{"field": "email", "description": "must be a valid email address"}]
+//		}],
+//		"request_id": "3c5d0a6e-..."
+//	}
+type ErrorBody struct {
+	Code      codes.Code `json:"code"`
+	Message   string     `json:"message"`
+	Details   []any      `json:"details,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+}
+
+func newErrorBody(s status.Status, requestID string) ErrorBody {
+	message := s.Message
+	if message == "" {
+		message = http.StatusText(int(s.Code))
+	}
+	return ErrorBody{
+		Code:      s.Code,
+		Message:   message,
+		Details:   s.Details(),
+		RequestID: requestID,
+	}
+}
+
+// sendFiberError writes s as an ErrorBody.
+func sendFiberError(c *fiber.Ctx, s status.Status) error {
+	requestID := c.GetRespHeader(fiber.HeaderXRequestID, c.Get(fiber.HeaderXRequestID))
+	if retryAfter, ok := retryAfter(s); ok && c.GetRespHeader(fiber.HeaderRetryAfter) == "" {
+		c.Set(fiber.HeaderRetryAfter, retryAfter)
+	}
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return c.Status(int(s.Code)).JSON(newErrorBody(s, requestID))
+}
+
+// writeHTTPError writes s as an ErrorBody.
+func writeHTTPError(w http.ResponseWriter, r *http.Request, s status.Status) {
+	requestID := w.Header().Get("X-Request-Id")
+	if requestID == "" {
+		requestID = r.Header.Get("X-Request-Id")
+	}
+	if retryAfter, ok := retryAfter(s); ok && w.Header().Get("Retry-After") == "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	data, _ := json.Marshal(newErrorBody(s, requestID))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(int(s.Code))
+	w.Write(data)
+}
+
+// retryAfter is the Retry-After header of the status.RetryInfo
+// of s, in whole seconds.
+func retryAfter(s status.Status) (string, bool) {
+	info, ok := status.DetailOf[status.RetryInfo](s)
+	if !ok {
+		return "", false
+	}
+	seconds := int64(math.Ceil(info.RetryDelay.Seconds()))
+	if seconds < 0 {
+		seconds = 0
+	}
+	return strconv.FormatInt(seconds, 10), true
+}
+
+// validationStatus breaks a validator error down into a
+// status.BadRequest with a violation per invalid field.
+func validationStatus(err error) status.Status {
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return status.Newf(codes.BadRequest, "validation failed: %v", err)
+	}
+	var details status.BadRequest
+	for _, fe := range invalid {
+		details.FieldViolations = append(details.FieldViolations, status.FieldViolation{
+			Field:       fieldPath(fe.Namespace()),
+			Description: describeViolation(fe),
+		})
+	}
+	return status.New(codes.BadRequest, "validation failed").WithDetails(details)
+}
+
+// fieldPath drops the struct name a validator namespace starts
+// with, e.g. UpdateByUUIDRequest.user.email -> user.email
+func fieldPath(namespace string) string {
+	_, field, ok := strings.Cut(namespace, ".")
+	if !ok {
+		return namespace
+	}
+	return field
+}
+
+func describeViolation(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "uuid", "uuid3", "uuid4", "uuid5":
+		return "must be a valid UUID"
+	case "url", "uri", "http_url":
+		return "must be a valid URL"
+	case "oneof":
+		return fmt.Sprintf("must be one of [%v]", fe.Param())
+	case "len":
+		return fmt.Sprintf("must have a length of %v", fe.Param())
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %v", fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %v", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %v", fe.Param())
+	case "lt":
+		return fmt.Sprintf("must be less than %v", fe.Param())
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed the '%v=%v' validation", fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("failed the '%v' validation", fe.Tag())
+}
+
+// jsonFieldName names validated fields after their json tag,
+// so field violations match the body the client sent.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
@@ -0,0 +1,11 @@
+package fit
+
+import _ "embed"
+
+// explorerHTML is served at GET /<type>/help/ui, a page listing
+// the methods, their INPUT/OUTPUT and examples, which can call
+// them. It loads everything from the help endpoints, and nothing
+// from elsewhere.
+//
+//go:embed explorer.html
+var explorerHTML []byte
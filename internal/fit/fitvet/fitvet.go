@@ -0,0 +1,254 @@
+// Package fitvet checks at build time that the exported methods of
+// the types given to fit.NewRPC are served, as fit.WithStrict does
+// when they start.
+package fitvet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+const (
+	fitPath    = "github.com/hyqe/ribose/internal/fit"
+	statusPath = fitPath + "/status"
+)
+
+// Diagnostic is a problem found at Pos.
+type Diagnostic struct {
+	Pos     token.Pos
+	Message string
+}
+
+// Check finds the calls of fit.NewRPC in files, a type checked
+// package, and reports the exported methods they skip, and the
+// WithMethod options naming methods they don't serve.
+func Check(files []*ast.File, info *types.Info) []Diagnostic {
+	var diags []Diagnostic
+	report := func(pos token.Pos, format string, args ...any) {
+		diags = append(diags, Diagnostic{Pos: pos, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fn := calledFit(info, call, "NewRPC")
+			if fn == nil || len(call.Args) == 0 {
+				return true
+			}
+			svc := info.TypeOf(call.Args[0])
+			if svc == nil || types.IsInterface(svc) {
+				return true
+			}
+			c := newChecker(fn.Pkg())
+			if c == nil {
+				return true
+			}
+
+			served := make(map[string]bool)
+			skipped := make(map[string]bool)
+			methods := types.NewMethodSet(svc)
+			for i := 0; i < methods.Len(); i++ {
+				m := methods.At(i).Obj()
+				if !m.Exported() {
+					continue
+				}
+				if err := c.check(m.Type().(*types.Signature), qualifier); err != nil {
+					skipped[m.Name()] = true
+					report(call.Args[0].Pos(), "NewRPC skips %v.%v: %v", typeName(svc), m.Name(), err)
+					continue
+				}
+				served[m.Name()] = true
+			}
+
+			for _, arg := range call.Args[1:] {
+				opt, ok := arg.(*ast.CallExpr)
+				if !ok || calledFit(info, opt, "WithMethod") == nil || len(opt.Args) == 0 {
+					continue
+				}
+				tv := info.Types[opt.Args[0]]
+				if tv.Value == nil || tv.Value.Kind() != constant.String {
+					continue
+				}
+				name := constant.StringVal(tv.Value)
+				if !served[name] && !skipped[name] {
+					report(opt.Args[0].Pos(), "WithMethod: %v has no method %v", typeName(svc), name)
+				}
+			}
+			return true
+		})
+	}
+	return diags
+}
+
+// calledFit is the function of package fit called name which call
+// calls, if any.
+func calledFit(info *types.Info, call *ast.CallExpr, name string) *types.Func {
+	fun := call.Fun
+	for {
+		p, ok := fun.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		fun = p.X
+	}
+	var id *ast.Ident
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != fitPath || fn.Name() != name {
+		return nil
+	}
+	return fn
+}
+
+// checker checks signatures the way fit's newMethod does.
+type checker struct {
+	context *types.Interface
+	status  types.Type
+	error   types.Type
+}
+
+// newChecker finds the types it needs in the imports of fit.
+func newChecker(fit *types.Package) *checker {
+	c := &checker{error: types.Universe.Lookup("error").Type()}
+	for _, pkg := range fit.Imports() {
+		switch pkg.Path() {
+		case "context":
+			c.context = pkg.Scope().Lookup("Context").Type().Underlying().(*types.Interface)
+		case statusPath:
+			c.status = pkg.Scope().Lookup("Status").Type()
+		}
+	}
+	if c.context == nil || c.status == nil {
+		return nil
+	}
+	return c
+}
+
+func (c *checker) check(sig *types.Signature, q types.Qualifier) error {
+	var params []types.Type
+	for i := 0; i < sig.Params().Len(); i++ {
+		params = append(params, sig.Params().At(i).Type())
+	}
+
+	if len(params) == 0 {
+		return fmt.Errorf("takes no arguments, want ctx first")
+	}
+	if !types.Implements(params[0], c.context) {
+		return fmt.Errorf("ctx is %v, want context.Context", types.TypeString(params[0], q))
+	}
+	params = params[1:]
+
+	if len(params) > 0 {
+		if _, ok := params[0].Underlying().(*types.Signature); !ok {
+			if !isStructPointer(params[0]) && !isStruct(params[0]) {
+				return fmt.Errorf("in is %v, want a struct or a pointer to one", types.TypeString(params[0], q))
+			}
+			params = params[1:]
+		}
+	}
+
+	var send *types.Signature
+	if len(params) > 0 {
+		var ok bool
+		send, ok = params[0].Underlying().(*types.Signature)
+		if !ok || send.Params().Len() != 1 || send.Results().Len() != 1 || !types.Identical(send.Results().At(0).Type(), c.error) {
+			return fmt.Errorf("send is %v, want func(OUTPUT) error", types.TypeString(params[0], q))
+		}
+		params = params[1:]
+	}
+	if len(params) > 0 {
+		return fmt.Errorf("takes %v arguments, want (ctx, in, send) at most", sig.Params().Len())
+	}
+
+	results := sig.Results()
+	numOut := results.Len()
+	if numOut == 0 || (!types.Identical(results.At(numOut-1).Type(), c.status) && !types.Identical(results.At(numOut-1).Type(), c.error)) {
+		return fmt.Errorf("returns %v, want status.Status or error last", tupleString(results, q))
+	}
+
+	var (
+		out    types.Type
+		stream bool
+	)
+	switch {
+	case send != nil:
+		if numOut != 1 {
+			return fmt.Errorf("returns %v, want only status.Status or error when streaming", tupleString(results, q))
+		}
+		out, stream = send.Params().At(0).Type(), true
+	case numOut == 2:
+		out = results.At(0).Type()
+		if ch, ok := out.Underlying().(*types.Chan); ok && ch.Dir() != types.SendOnly {
+			out, stream = ch.Elem(), true
+		}
+	case numOut > 2:
+		return fmt.Errorf("returns %v, want (OUTPUT, status.Status) at most", tupleString(results, q))
+	}
+
+	switch {
+	case out == nil:
+	case isStructPointer(out), isStruct(out):
+	case isSlice(out) && !stream:
+	default:
+		return fmt.Errorf("OUTPUT is %v, want a struct, a pointer to one, or a slice", types.TypeString(out, q))
+	}
+	return nil
+}
+
+func isStruct(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+func isSlice(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Slice)
+	return ok
+}
+
+func isStructPointer(t types.Type) bool {
+	p, ok := t.Underlying().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	_, ok = p.Elem().Underlying().(*types.Struct)
+	return ok
+}
+
+// tupleString formats results the way reflect does, e.g. (*User, error)
+func tupleString(results *types.Tuple, q types.Qualifier) string {
+	out := make([]string, results.Len())
+	for i := range out {
+		out[i] = types.TypeString(results.At(i).Type(), q)
+	}
+	return "(" + strings.Join(out, ", ") + ")"
+}
+
+// qualifier names types by their package name, as reflect does.
+func qualifier(pkg *types.Package) string {
+	return pkg.Name()
+}
+
+// typeName is the name of svc the way fit.ServiceName has it,
+// e.g. users.Service
+func typeName(svc types.Type) string {
+	if p, ok := svc.(*types.Pointer); ok {
+		svc = p.Elem()
+	}
+	if named, ok := svc.(*types.Named); ok && named.Obj().Pkg() != nil {
+		return named.Obj().Pkg().Name() + "." + named.Obj().Name()
+	}
+	return types.TypeString(svc, qualifier)
+}
@@ -0,0 +1,107 @@
+package fitvet
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestVet checks the packages of testdata/src, whose lines say
+// what they should report in comments, as analysistest does.
+//
+//	fit.WithMethod("Nope"), // want `has no method Nope`
+func TestVet(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "src", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			wants, err := parseWants(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fset, diags, err := Vet("./" + filepath.ToSlash(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, d := range diags {
+				pos := fset.Position(d.Pos)
+				k := lineKey{filepath.Base(pos.Filename), pos.Line}
+				if !wants.match(k, d.Message) {
+					t.Errorf("%v:%v: unexpected diagnostic: %v", k.file, k.line, d.Message)
+				}
+			}
+			for k, res := range wants {
+				for _, re := range res {
+					t.Errorf("%v:%v: no diagnostic was reported matching %#q", k.file, k.line, re)
+				}
+			}
+		})
+	}
+}
+
+type lineKey struct {
+	file string
+	line int
+}
+
+// wants are the expected diagnostics of each line, which are
+// removed once they are reported.
+type wants map[lineKey][]*regexp.Regexp
+
+func (w wants) match(k lineKey, message string) bool {
+	for i, re := range w[k] {
+		if re.MatchString(message) {
+			w[k] = append(w[k][:i], w[k][i+1:]...)
+			if len(w[k]) == 0 {
+				delete(w, k)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+var wantRegexp = regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`")
+
+// parseWants finds the want comments of the go files in dir.
+func parseWants(dir string) (wants, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	w := make(wants)
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, group := range f.Comments {
+				for _, c := range group.List {
+					text, ok := strings.CutPrefix(c.Text, "// want ")
+					if !ok {
+						continue
+					}
+					pos := fset.Position(c.Pos())
+					k := lineKey{filepath.Base(pos.Filename), pos.Line}
+					for _, quoted := range wantRegexp.FindAllString(text, -1) {
+						pattern, err := strconv.Unquote(quoted)
+						if err != nil {
+							return nil, fmt.Errorf("%v: %v", pos, err)
+						}
+						re, err := regexp.Compile(pattern)
+						if err != nil {
+							return nil, fmt.Errorf("%v: %v", pos, err)
+						}
+						w[k] = append(w[k], re)
+					}
+				}
+			}
+		}
+	}
+	return w, nil
+}
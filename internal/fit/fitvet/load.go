@@ -0,0 +1,113 @@
+package fitvet
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Vet checks the packages matching patterns, as go list finds
+// them, and reports the positions of their diagnostics in fset.
+func Vet(patterns ...string) (fset *token.FileSet, diags []Diagnostic, err error) {
+	pkgs, err := listPackages(patterns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list packages: %v", err)
+	}
+	exports := make(map[string]string)
+	for _, pkg := range pkgs {
+		exports[pkg.ImportPath] = pkg.Export
+	}
+
+	fset = token.NewFileSet()
+	for _, pkg := range pkgs {
+		if pkg.DepOnly {
+			continue
+		}
+		if pkg.Error != nil {
+			return nil, nil, fmt.Errorf("%v: %v", pkg.ImportPath, pkg.Error.Err)
+		}
+		found, err := check(fset, pkg, exports)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check %v: %v", pkg.ImportPath, err)
+		}
+		diags = append(diags, found...)
+	}
+	return fset, diags, nil
+}
+
+// listedPackage is what go list says of a package.
+type listedPackage struct {
+	Dir        string
+	ImportPath string
+	Export     string
+	GoFiles    []string
+	ImportMap  map[string]string
+	DepOnly    bool
+	Error      *struct {
+		Err string
+	}
+}
+
+// listPackages lists the packages matching patterns, and their
+// dependencies, built so they can be imported from their export data.
+func listPackages(patterns []string) ([]*listedPackage, error) {
+	cmd := exec.Command("go", append([]string{"list", "-e", "-json", "-export", "-deps"}, patterns...)...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	var pkgs []*listedPackage
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var pkg listedPackage
+		if err := dec.Decode(&pkg); err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, &pkg)
+	}
+	return pkgs, cmd.Wait()
+}
+
+func check(fset *token.FileSet, pkg *listedPackage, exports map[string]string) ([]Diagnostic, error) {
+	var files []*ast.File
+	for _, name := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	lookup := func(path string) (io.ReadCloser, error) {
+		if mapped, ok := pkg.ImportMap[path]; ok {
+			path = mapped
+		}
+		export, ok := exports[path]
+		if !ok || export == "" {
+			return nil, fmt.Errorf("no export data for %v", path)
+		}
+		return os.Open(export)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", lookup)}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	_, err := conf.Check(pkg.ImportPath, fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+	return Check(files, info), nil
+}
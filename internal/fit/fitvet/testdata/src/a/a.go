@@ -0,0 +1,64 @@
+package a
+
+import (
+	"context"
+
+	"github.com/hyqe/ribose/internal/fit"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+type In struct{}
+
+type Out struct{}
+
+type Service struct{}
+
+func (Service) Create(ctx context.Context, in *In) (*Out, status.Status) { return nil, status.OK }
+
+func (Service) List(ctx context.Context) ([]Out, error) { return nil, nil }
+
+func (Service) Watch(ctx context.Context, in In, send func(*Out) error) status.Status {
+	return status.OK
+}
+
+func (Service) Changes(ctx context.Context) (<-chan Out, status.Status) { return nil, status.OK }
+
+func (Service) Ping(ctx context.Context, in string) (*Out, status.Status) { return nil, status.OK }
+
+func (Service) NoContext(in *In) (*Out, status.Status) { return nil, status.OK }
+
+func (Service) NoStatus(ctx context.Context, in *In) *Out { return nil }
+
+func (Service) Text(ctx context.Context, in *In) (string, status.Status) { return "", status.OK }
+
+func (Service) TooMany(ctx context.Context, in *In) (*Out, *Out, status.Status) {
+	return nil, nil, status.OK
+}
+
+func (Service) BadSend(ctx context.Context, in *In, send func(*Out)) status.Status { return status.OK }
+
+func (Service) SliceStream(ctx context.Context, send func([]Out) error) status.Status {
+	return status.OK
+}
+
+func (Service) internal(in string) {}
+
+var _ = fit.NewRPC(Service{}, // want `NewRPC skips a.Service.Ping: in is string, want a struct or a pointer to one` `NewRPC skips a.Service.NoContext: ctx is \*a.In, want context.Context` `NewRPC skips a.Service.NoStatus: returns \(\*a.Out\), want status.Status or error last` `NewRPC skips a.Service.Text: OUTPUT is string, want a struct, a pointer to one, or a slice` `NewRPC skips a.Service.TooMany: returns \(\*a.Out, \*a.Out, status.Status\), want \(OUTPUT, status.Status\) at most` `NewRPC skips a.Service.BadSend: send is func\(\*a.Out\), want func\(OUTPUT\) error` `NewRPC skips a.Service.SliceStream: OUTPUT is \[\]a.Out, want a struct, a pointer to one, or a slice`
+	fit.WithMethod("Create", fit.Timeout(0)),
+	fit.WithMethod("Ping"),
+	fit.WithMethod("Nope"),     // want `WithMethod: a.Service has no method Nope`
+	fit.WithMethod("internal"), // want `WithMethod: a.Service has no method internal`
+)
+
+type Fine struct{}
+
+func (*Fine) Get(ctx context.Context, in *In) (*Out, error) { return nil, nil }
+
+var _ = fit.NewRPC(&Fine{}, fit.WithMethod("Get"))
+
+// NewRPC isn't fit.NewRPC, so it isn't checked.
+func NewRPC(svc any, names ...string) {}
+
+func init() {
+	NewRPC(Service{}, "Nope")
+}
@@ -16,13 +16,17 @@ func Fiber[I, O any](fn Handler[I, O]) fiber.Handler {
 		var in I
 		err := c.BodyParser(&in)
 		if err != nil {
-			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+			return sendFiberError(c, status.New(codes.BadRequest, err))
 		}
-		switch out, status := fn(c.Context(), in); status.Code {
+		ctx, md, cancel := fiberContext(c, TransportHTTP)
+		defer cancel()
+		out, s := fn(ctx, in)
+		setFiberHeader(c, md)
+		switch s.Code {
 		case codes.OK, codes.Created, codes.Accepted:
 			return c.JSON(out)
 		default:
-			return fiber.NewError(int(status.Code), status.Error())
+			return sendFiberError(c, s)
 		}
 	}
 }
@@ -0,0 +1,230 @@
+package fit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// IdempotencyKeyHeader lets a client retry a call of an Idempotent
+// method without it running twice.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set to true on the responses kept
+// from the first call with an Idempotency-Key.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength bounds the keys clients can send.
+const maxIdempotencyKeyLength = 255
+
+// Idempotent makes a unary method honor the Idempotency-Key
+// header. The first call with a key runs the method, and store
+// keeps its response, which every retry with the same key gets
+// instead of running it again.
+//
+//	store := fit.NewMemoryIdempotencyStore(24 * time.Hour)
+//	fit.WithMethod("Create", fit.Idempotent(store))
+//
+// Keys are scoped to the method and the Principal of the call,
+// see Metadata, which is told apart by fmt.Sprint. Retrying with
+// another INPUT fails with codes.UnprocessableEntity, and while
+// the first call runs with codes.Conflict. Responses with a code
+// >= 500 aren't kept, so they can be retried.
+//
+// The calls of a batch share its header, so each is kept apart by
+// its index in the batch, and retrying the batch replays all of
+// them.
+//
+// Calls without the header run as usual.
+func Idempotent(store IdempotencyStore) MethodOption {
+	return func(m *Method) {
+		m.idempotency = store
+	}
+}
+
+// IdempotencyKey is what a call is kept under.
+type IdempotencyKey struct {
+	// Principal is the Principal of the call with fmt.Sprint, or
+	// "" without one.
+	Principal string
+	// Method is the full method, e.g. /users.Service/Create
+	Method string
+	Key    string
+	// BatchItem is one more than the index of the call in its
+	// batch, or 0 outside of one.
+	BatchItem int
+}
+
+// IdempotencyRecord is what an IdempotencyStore keeps of a call.
+type IdempotencyRecord struct {
+	// RequestHash is the SHA-256 of the INPUT of the call as JSON.
+	RequestHash []byte
+	// Done is set once the call returned Status and Output.
+	Done   bool
+	Status status.Status
+	// Output is the OUTPUT of the call as JSON.
+	Output json.RawMessage
+}
+
+// IdempotencyStore keeps the responses of Idempotent methods. Its
+// records should expire, so keys can be used again, and so a call
+// whose instance died can be retried.
+type IdempotencyStore interface {
+	// Claim keeps a record of a call under key, unless there is
+	// one already, which it returns instead. It returns nil when
+	// key was claimed.
+	Claim(ctx context.Context, key IdempotencyKey, requestHash []byte) (*IdempotencyRecord, error)
+	// Complete keeps the response of the call which claimed key.
+	Complete(ctx context.Context, key IdempotencyKey, st status.Status, output json.RawMessage) error
+	// Release forgets key, so it can be claimed again.
+	Release(ctx context.Context, key IdempotencyKey) error
+}
+
+// idempotent calls invoke once per Idempotency-Key, answering the
+// retries with what the store kept.
+func (s *RPC) idempotent(ctx context.Context, info *CallInfo, method *Method, in any, invoke Invoker) (any, status.Status) {
+	md := MetadataFromContext(ctx)
+	k := md.Header.Get(IdempotencyKeyHeader)
+	if method.idempotency == nil || method.stream != unary || k == "" {
+		return invoke(ctx, in)
+	}
+	if len(k) > maxIdempotencyKeyLength {
+		return nil, status.Newf(codes.BadRequest, "invalid %v: longer than %v bytes", IdempotencyKeyHeader, maxIdempotencyKeyLength)
+	}
+	key := IdempotencyKey{Method: info.FullMethod(), Key: k}
+	if i, ok := ctx.Value(batchItemKey{}).(int); ok {
+		key.BatchItem = i + 1
+	}
+	if md.Principal != nil {
+		key.Principal = fmt.Sprint(md.Principal)
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, status.Newf(codes.Internal, "failed to hash request: %v", err)
+	}
+	hash := sha256.Sum256(data)
+
+	rec, err := method.idempotency.Claim(ctx, key, hash[:])
+	if err != nil {
+		return nil, status.Newf(codes.Internal, "failed to claim %v: %v", IdempotencyKeyHeader, err)
+	}
+	if rec != nil {
+		return replay(ctx, method, rec, hash[:])
+	}
+
+	out, st := invoke(ctx, in)
+	// the store outlives the call, so its ctx may be done.
+	storeCtx := context.Background()
+	if st.Code >= 500 || (st.Code >= 300 && ctx.Err() != nil) {
+		if err := method.idempotency.Release(storeCtx, key); err != nil {
+			log.Printf("fit: failed to release %v %q of %v: %v", IdempotencyKeyHeader, key.Key, key.Method, err)
+		}
+		return out, st
+	}
+	output, err := json.Marshal(out)
+	if err == nil {
+		err = method.idempotency.Complete(storeCtx, key, st, output)
+	}
+	if err != nil {
+		log.Printf("fit: failed to keep the response of %v %q of %v: %v", IdempotencyKeyHeader, key.Key, key.Method, err)
+		method.idempotency.Release(storeCtx, key)
+	}
+	return out, st
+}
+
+// batchItemKey is the index of a call in its batch.
+type batchItemKey struct{}
+
+func withBatchItem(ctx context.Context, i int) context.Context {
+	return context.WithValue(ctx, batchItemKey{}, i)
+}
+
+// replay answers a retry with the response of the first call.
+func replay(ctx context.Context, method *Method, rec *IdempotencyRecord, requestHash []byte) (any, status.Status) {
+	if !bytes.Equal(rec.RequestHash, requestHash) {
+		return nil, status.Newf(codes.UnprocessableEntity, "%v was used with another request", IdempotencyKeyHeader)
+	}
+	if !rec.Done {
+		return nil, status.Newf(codes.Conflict, "a request with this %v is in progress", IdempotencyKeyHeader).
+			WithDetails(status.RetryInfo{RetryDelay: time.Second})
+	}
+	MetadataFromContext(ctx).SetHeader(IdempotentReplayedHeader, "true")
+	if rec.Status.Code >= 300 || method.outType == nil {
+		return nil, rec.Status
+	}
+	out := reflect.New(method.outType)
+	if err := json.Unmarshal(rec.Output, out.Interface()); err != nil {
+		return nil, status.Newf(codes.Internal, "failed to decode kept response: %v", err)
+	}
+	return out.Elem().Interface(), rec.Status
+}
+
+// MemoryIdempotencyStore keeps records in memory for ttl. It only
+// suits a single instance, see IdempotencyStore.
+type MemoryIdempotencyStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	records   map[IdempotencyKey]*memoryRecord
+	nextSweep time.Time
+}
+
+type memoryRecord struct {
+	IdempotencyRecord
+	expires time.Time
+}
+
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		records: make(map[IdempotencyKey]*memoryRecord),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Claim(ctx context.Context, key IdempotencyKey, requestHash []byte) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for k, r := range s.records {
+			if now.After(r.expires) {
+				delete(s.records, k)
+			}
+		}
+		s.nextSweep = now.Add(s.ttl)
+	}
+	if r, ok := s.records[key]; ok && now.Before(r.expires) {
+		rec := r.IdempotencyRecord
+		return &rec, nil
+	}
+	s.records[key] = &memoryRecord{
+		IdempotencyRecord: IdempotencyRecord{RequestHash: requestHash},
+		expires:           now.Add(s.ttl),
+	}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key IdempotencyKey, st status.Status, output json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.records[key]; ok {
+		r.Done, r.Status, r.Output = true, st, output
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
@@ -0,0 +1,60 @@
+package fit
+
+import (
+	"context"
+
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// Invoker calls the next Interceptor in the chain, and finally
+// the method itself.
+type Invoker func(ctx context.Context, in any) (any, status.Status)
+
+// Interceptor runs around every method call of an RPC, regardless
+// of the transport it was served on. It sees the decoded INPUT
+// before it is validated, and the OUTPUT and status.Status the
+// method returned.
+//
+// Calling next continues the call, returning without calling it
+// short-circuits the method.
+//
+//	func logging(ctx context.Context, info *fit.CallInfo, in any, next fit.Invoker) (any, status.Status) {
+//		out, s := next(ctx, in)
+//		log.Printf("%v: %v", info.FullMethod(), s.Code)
+//		return out, s
+//	}
+type Interceptor func(ctx context.Context, info *CallInfo, in any, next Invoker) (any, status.Status)
+
+// CallInfo describes the method being called.
+type CallInfo struct {
+	Service string // e.g. users.Service
+	Method  string // e.g. Create
+	// Stream is set for streaming methods, whose OUTPUT is
+	// always nil as it is sent as the method runs.
+	Stream bool
+}
+
+// FullMethod is the path the method is served on,
+// e.g. /users.Service/Create
+func (c *CallInfo) FullMethod() string {
+	return "/" + c.Service + "/" + c.Method
+}
+
+// WithInterceptors appends interceptors to the chain. The first
+// interceptor is the outermost.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(r *RPC) {
+		r.interceptors = append(r.interceptors, interceptors...)
+	}
+}
+
+// chain wraps invoke with interceptors, first being outermost.
+func chain(interceptors []Interceptor, info *CallInfo, invoke Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoke
+		invoke = func(ctx context.Context, in any) (any, status.Status) {
+			return interceptor(ctx, info, in, next)
+		}
+	}
+	return invoke
+}
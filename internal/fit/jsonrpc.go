@@ -0,0 +1,307 @@
+package fit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// JSON-RPC 2.0 error codes.
+// https://www.jsonrpc.org/specification#error_object
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+)
+
+// JSONRPC serves the methods of many RPCs over a single
+// JSON-RPC 2.0 endpoint. Methods are named <type>.<method>.
+//
+//	{"jsonrpc": "2.0", "method": "users.Service.Create", "params": {"email": "foo@example.com"}, "id": 1}
+//
+// Batches and notifications are supported. A status.Status
+// with a code >= 300 is returned as an error object whose
+// code is the status code, except for codes.BadRequest and
+// codes.Internal which use the JSON-RPC invalid params and
+// internal error codes. The status code and details are
+// always in data. Streaming methods aren't served, and are
+// not found.
+//
+// Batches are limited like those of its RPCs, by the smallest
+// WithMaxBatchSize and WithBatchConcurrency among them.
+type JSONRPC struct {
+	methods map[string]jsonRPCMethod
+
+	maxBatchSize     int
+	batchConcurrency int
+}
+
+type jsonRPCMethod struct {
+	rpc    *RPC
+	method *Method
+}
+
+func NewJSONRPC(rpcs ...*RPC) *JSONRPC {
+	j := &JSONRPC{
+		methods: make(map[string]jsonRPCMethod),
+
+		maxBatchSize:     DefaultMaxBatchSize,
+		batchConcurrency: 1,
+	}
+	for i, rpc := range rpcs {
+		if i == 0 || (rpc.maxBatchSize > 0 && (j.maxBatchSize <= 0 || rpc.maxBatchSize < j.maxBatchSize)) {
+			j.maxBatchSize = rpc.maxBatchSize
+		}
+		if i == 0 || rpc.batchConcurrency < j.batchConcurrency {
+			j.batchConcurrency = rpc.batchConcurrency
+		}
+		for name, method := range rpc.methods {
+			if method.stream != unary {
+				continue
+			}
+			j.methods[rpc.Name()+"."+name] = jsonRPCMethod{
+				rpc:    rpc,
+				method: method,
+			}
+		}
+	}
+	return j
+}
+
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	// ID is nil for notifications, and null when the
+	// client sent "id": null.
+	ID json.RawMessage `json:"id,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type JSONRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+type jsonRPCErrorData struct {
+	Status  codes.Code `json:"status"`
+	Details []any      `json:"details,omitempty"`
+}
+
+func (j *JSONRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeHTTPError(w, r, status.Status{Code: codes.MethodNotAllowed})
+		return
+	}
+	body, st := readHTTPBody(w, r)
+	if st.Code >= 300 {
+		writeHTTPError(w, r, st)
+		return
+	}
+	ctx, md := httpContext(w, r, TransportJSONRPC)
+	resp := j.Handle(ctx, body)
+	setHTTPHeader(w, md)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// FiberHandler serves the endpoint on a fiber route.
+//
+//	app.Post("/jsonrpc", fit.NewJSONRPC(rpcs...).FiberHandler())
+func (j *JSONRPC) FiberHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, md, cancel := fiberContext(c, TransportJSONRPC)
+		defer cancel()
+		resp := j.Handle(ctx, c.Body())
+		setFiberHeader(c, md)
+		if resp == nil {
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+		c.Type("json")
+		return c.Send(resp)
+	}
+}
+
+// Handle processes a single or batch request, returning nil
+// when there is nothing to respond with.
+func (j *JSONRPC) Handle(ctx context.Context, body []byte) []byte {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		return j.handleBatch(ctx, body)
+	}
+
+	var req jsonRPCRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return mustMarshal(jsonRPCFailure(nil, JSONRPCParseError, fmt.Sprintf("parse error: %v", err), nil))
+	}
+	resp := j.handle(ctx, req)
+	if resp == nil {
+		return nil
+	}
+	return mustMarshal(resp)
+}
+
+func (j *JSONRPC) handleBatch(ctx context.Context, body []byte) []byte {
+	var reqs []json.RawMessage
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		return mustMarshal(jsonRPCFailure(nil, JSONRPCParseError, fmt.Sprintf("parse error: %v", err), nil))
+	}
+	if len(reqs) == 0 {
+		return mustMarshal(jsonRPCFailure(nil, JSONRPCInvalidRequest, "invalid request: empty batch", nil))
+	}
+	if j.maxBatchSize > 0 && len(reqs) > j.maxBatchSize {
+		return mustMarshal(jsonRPCStatus(nil, status.Newf(codes.RequestEntityTooLarge, "batch of %v calls exceeds the limit of %v", len(reqs), j.maxBatchSize)))
+	}
+
+	concurrency := j.batchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	resps := make([]*jsonRPCResponse, len(reqs))
+	var wg sync.WaitGroup
+	for i := range reqs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			var req jsonRPCRequest
+			if err := json.Unmarshal(reqs[i], &req); err != nil {
+				resps[i] = jsonRPCFailure(nil, JSONRPCInvalidRequest, fmt.Sprintf("invalid request: %v", err), nil)
+				return
+			}
+			resps[i] = j.handle(withBatchItem(ctx, i), req)
+		}(i)
+	}
+	wg.Wait()
+
+	out := make([]*jsonRPCResponse, 0, len(resps))
+	for _, resp := range resps {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return mustMarshal(out)
+}
+
+// handle calls the method of a single request, returning nil
+// for notifications. Invalid requests are always answered.
+func (j *JSONRPC) handle(ctx context.Context, req jsonRPCRequest) *jsonRPCResponse {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return jsonRPCFailure(req.ID, JSONRPCInvalidRequest, "invalid request", nil)
+	}
+	resp := j.call(ctx, req)
+	if req.ID == nil {
+		return nil
+	}
+	return resp
+}
+
+func (j *JSONRPC) call(ctx context.Context, req jsonRPCRequest) *jsonRPCResponse {
+	target, ok := j.methods[req.Method]
+	if !ok {
+		return jsonRPCFailure(req.ID, JSONRPCMethodNotFound, "method not found: "+req.Method, nil)
+	}
+
+	in := target.method.newIn()
+	if params := bytes.TrimSpace(req.Params); len(params) > 0 && !bytes.Equal(params, []byte("null")) {
+		// by position, the only param is the INPUT.
+		if params[0] == '[' {
+			var positional []json.RawMessage
+			if err := json.Unmarshal(params, &positional); err != nil || len(positional) != 1 {
+				return jsonRPCFailure(req.ID, JSONRPCInvalidParams, "invalid params: want an object or an array of one object", nil)
+			}
+			params = positional[0]
+		}
+		if err := json.Unmarshal(params, in); err != nil {
+			return jsonRPCFailure(req.ID, JSONRPCInvalidParams, fmt.Sprintf("failed to decode params: %v", err), nil)
+		}
+	}
+
+	out, s := target.rpc.call(ctx, target.method, in)
+	if s.Code >= 300 {
+		return jsonRPCStatus(req.ID, s)
+	}
+	if s.Code == codes.NoContent {
+		out = nil
+	}
+	return &jsonRPCResponse{
+		JSONRPC: "2.0",
+		Result:  jsonRPCResult{out},
+		ID:      req.ID,
+	}
+}
+
+// jsonRPCResult always encodes, even as null, since a
+// successful response must have a result member.
+type jsonRPCResult struct{ v any }
+
+func (r jsonRPCResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.v)
+}
+
+func jsonRPCStatus(id json.RawMessage, s status.Status) *jsonRPCResponse {
+	code := int(s.Code)
+	switch s.Code {
+	case codes.BadRequest:
+		code = JSONRPCInvalidParams
+	case codes.Internal:
+		code = JSONRPCInternalError
+	}
+	message := s.Message
+	if message == "" {
+		message = http.StatusText(int(s.Code))
+	}
+	return jsonRPCFailure(id, code, message, jsonRPCErrorData{Status: s.Code, Details: s.Details()})
+}
+
+func jsonRPCFailure(id json.RawMessage, code int, message string, data any) *jsonRPCResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &jsonRPCResponse{
+		JSONRPC: "2.0",
+		Error: &JSONRPCError{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+		ID: id,
+	}
+}
+
+func mustMarshal(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(jsonRPCFailure(nil, JSONRPCInternalError, fmt.Sprintf("failed to encode response: %v", err), nil))
+	}
+	return data
+}
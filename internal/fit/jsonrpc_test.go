@@ -0,0 +1,255 @@
+package fit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+type jsonRPCService struct {
+	// running and peak count the calls of Slow.
+	running, peak *atomic.Int64
+}
+
+type jsonRPCEchoRequest struct {
+	Message string `json:"message" validate:"required"`
+}
+
+type jsonRPCEchoResponse struct {
+	Message string `json:"message"`
+}
+
+func (jsonRPCService) Echo(ctx context.Context, in *jsonRPCEchoRequest) (*jsonRPCEchoResponse, status.Status) {
+	return &jsonRPCEchoResponse{Message: in.Message}, status.OK
+}
+
+func (jsonRPCService) Delete(ctx context.Context, in *jsonRPCEchoRequest) status.Status {
+	return status.OK
+}
+
+func (jsonRPCService) Missing(ctx context.Context) error {
+	return status.New(codes.NotFound, "nothing here")
+}
+
+func (jsonRPCService) Broken(ctx context.Context) error {
+	return status.New(codes.Internal, "broken")
+}
+
+func (jsonRPCService) Count(ctx context.Context, in *jsonRPCEchoRequest, send func(*jsonRPCEchoResponse) error) status.Status {
+	return status.OK
+}
+
+func (s jsonRPCService) Slow(ctx context.Context) status.Status {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return status.OK
+}
+
+func newJSONRPCService() jsonRPCService {
+	return jsonRPCService{running: new(atomic.Int64), peak: new(atomic.Int64)}
+}
+
+func TestJSONRPCHandle(t *testing.T) {
+	j := NewJSONRPC(NewRPC(newJSONRPCService()))
+	tests := []struct {
+		name string
+		body string
+		// want is the response, or "" for none.
+		want string
+	}{
+		{
+			name: "call",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":{"message":"hi"},"id":1}`,
+			want: `{"jsonrpc":"2.0","result":{"message":"hi"},"id":1}`,
+		},
+		{
+			name: "positional params",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":[{"message":"hi"}],"id":"a"}`,
+			want: `{"jsonrpc":"2.0","result":{"message":"hi"},"id":"a"}`,
+		},
+		{
+			name: "no content",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Delete","params":{"message":"hi"},"id":1}`,
+			want: `{"jsonrpc":"2.0","result":null,"id":1}`,
+		},
+		{
+			name: "notification",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":{"message":"hi"}}`,
+		},
+		{
+			name: "failed notification",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Missing"}`,
+		},
+		{
+			name: "null id",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":{"message":"hi"},"id":null}`,
+			want: `{"jsonrpc":"2.0","result":{"message":"hi"},"id":null}`,
+		},
+		{
+			name: "parse error",
+			body: `{"jsonrpc":`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error: unexpected end of JSON input"},"id":null}`,
+		},
+		{
+			name: "invalid request",
+			body: `{"jsonrpc":"1.0","method":"fit.jsonRPCService.Echo","id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":1}`,
+		},
+		{
+			name: "method not found",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Nope","id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found: fit.jsonRPCService.Nope"},"id":1}`,
+		},
+		{
+			name: "streaming method",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Count","params":{"message":"hi"},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found: fit.jsonRPCService.Count"},"id":1}`,
+		},
+		{
+			name: "invalid params",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":[1,2],"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"invalid params: want an object or an array of one object"},"id":1}`,
+		},
+		{
+			name: "failed validation",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":{},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"validation failed","data":{"status":400,"details":[
+				{"@type":"BadRequest","field_violations":[{"field":"message","description":"is required"}]}
+			]}},"id":1}`,
+		},
+		{
+			name: "status",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Missing","id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":404,"message":"nothing here","data":{"status":404}},"id":1}`,
+		},
+		{
+			name: "internal",
+			body: `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Broken","id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32603,"message":"broken","data":{"status":500}},"id":1}`,
+		},
+		{
+			name: "batch",
+			body: `[
+				{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":{"message":"a"},"id":1},
+				{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":{"message":"b"}},
+				1,
+				{"jsonrpc":"2.0","method":"fit.jsonRPCService.Missing","id":2}
+			]`,
+			want: `[
+				{"jsonrpc":"2.0","result":{"message":"a"},"id":1},
+				{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: json: cannot unmarshal number into Go value of type fit.jsonRPCRequest"},"id":null},
+				{"jsonrpc":"2.0","error":{"code":404,"message":"nothing here","data":{"status":404}},"id":2}
+			]`,
+		},
+		{
+			name: "batch of notifications",
+			body: `[{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":{"message":"a"}}]`,
+		},
+		{
+			name: "empty batch",
+			body: `[]`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: empty batch"},"id":null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := j.Handle(context.Background(), []byte(tt.body))
+			if tt.want == "" {
+				if resp != nil {
+					t.Fatalf("got %s, want no response", resp)
+				}
+				return
+			}
+			assertJSON(t, rawJSON(resp), tt.want)
+		})
+	}
+}
+
+func TestJSONRPCServeHTTP(t *testing.T) {
+	j := NewJSONRPC(NewRPC(newJSONRPCService()))
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+	}{
+		{"call", http.MethodPost, `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":{"message":"hi"},"id":1}`, http.StatusOK},
+		{"error", http.MethodPost, `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Missing","id":1}`, http.StatusOK},
+		{"notification", http.MethodPost, `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Echo","params":{"message":"hi"}}`, http.StatusNoContent},
+		{"get", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"too large", http.MethodPost, `"` + strings.Repeat("a", maxHTTPBodySize) + `"`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			j.ServeHTTP(w, httptest.NewRequest(tt.method, "/jsonrpc", strings.NewReader(tt.body)))
+			if w.Code != tt.code {
+				t.Errorf("got code %v, want %v: %v", w.Code, tt.code, w.Body)
+			}
+		})
+	}
+}
+
+func TestJSONRPCBatchLimits(t *testing.T) {
+	call := `{"jsonrpc":"2.0","method":"fit.jsonRPCService.Slow","id":1}`
+	batch := func(n int) []byte {
+		return []byte("[" + strings.TrimSuffix(strings.Repeat(call+",", n), ",") + "]")
+	}
+
+	t.Run("size", func(t *testing.T) {
+		j := NewJSONRPC(NewRPC(newJSONRPCService(), WithMaxBatchSize(2)))
+		resp := j.Handle(context.Background(), batch(3))
+		assertJSON(t, rawJSON(resp), `{"jsonrpc":"2.0","error":{"code":413,"message":"batch of 3 calls exceeds the limit of 2","data":{"status":413}},"id":null}`)
+		if resp := j.Handle(context.Background(), batch(2)); strings.Contains(string(resp), "error") {
+			t.Errorf("got %s for a batch within the limit", resp)
+		}
+	})
+
+	t.Run("smallest size of its RPCs", func(t *testing.T) {
+		a := New("a", WithMaxBatchSize(0))
+		b := NewRPC(newJSONRPCService(), WithMaxBatchSize(2))
+		resp := NewJSONRPC(a, b).Handle(context.Background(), batch(3))
+		if !strings.Contains(string(resp), `"code":413`) {
+			t.Errorf("got %s, want 413", resp)
+		}
+	})
+
+	tests := []struct {
+		name string
+		opts []Option
+		want int64
+	}{
+		{"one at a time by default", nil, 1},
+		{"concurrency", []Option{WithBatchConcurrency(3)}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newJSONRPCService()
+			NewJSONRPC(NewRPC(svc, tt.opts...)).Handle(context.Background(), batch(6))
+			if peak := svc.peak.Load(); peak != tt.want {
+				t.Errorf("ran %v calls at once, want %v", peak, tt.want)
+			}
+		})
+	}
+}
+
+// rawJSON is JSON which encodes as is.
+type rawJSON []byte
+
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	return r, nil
+}
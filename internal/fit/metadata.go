@@ -0,0 +1,195 @@
+package fit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Transports a method can be called on, see Metadata.Transport.
+const (
+	TransportHTTP      = "http"
+	TransportJSONRPC   = "jsonrpc"
+	TransportWebSocket = "websocket"
+)
+
+// Metadata describes the request a method was called by, the
+// same way whatever the transport.
+//
+//	func (s *Service) Create(ctx context.Context, in *CreateRequest) (*CreateResponse, status.Status) {
+//		md := fit.MetadataFromContext(ctx)
+//		log.Printf("%v: create from %v", md.RequestID, md.RemoteAddr)
+//		md.SetHeader("Location", "/users/"+id)
+//		...
+//	}
+//
+// The Principal is left for an Interceptor to authenticate.
+//
+//	func auth(ctx context.Context, info *fit.CallInfo, in any, next fit.Invoker) (any, status.Status) {
+//		md := fit.MetadataFromContext(ctx)
+//		user, err := verify(md.Header.Get("Authorization"))
+//		if err != nil {
+//			return nil, status.New(codes.Unauthorized, err)
+//		}
+//		md.Principal = user
+//		return next(ctx, in)
+//	}
+type Metadata struct {
+	Transport string
+	// Header are the headers of the request, or of the upgrade
+	// request of a WebSocket.
+	Header     http.Header
+	RemoteAddr string
+	// RequestID is the X-Request-Id of the response, as set by
+	// a middleware, or else of the request.
+	RequestID string
+	Principal any
+
+	resp *responseMetadata
+}
+
+// responseMetadata is shared by the calls of a single request,
+// e.g. a batch.
+type responseMetadata struct {
+	mu      sync.Mutex
+	header  http.Header
+	cookies []*http.Cookie
+}
+
+type metadataKey struct{}
+
+// MetadataFromContext is the Metadata of the call ctx belongs to.
+// It is empty when ctx isn't from a call, e.g. in a test.
+func MetadataFromContext(ctx context.Context) *Metadata {
+	if md, ok := ctx.Value(metadataKey{}).(*Metadata); ok {
+		return md
+	}
+	return newMetadata("")
+}
+
+// ContextWithMetadata returns a copy of ctx carrying md, for
+// calling methods directly, e.g. in tests.
+func ContextWithMetadata(ctx context.Context, md *Metadata) context.Context {
+	if md.resp == nil {
+		md.resp = &responseMetadata{header: make(http.Header)}
+	}
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+func newMetadata(transport string) *Metadata {
+	return &Metadata{
+		Transport: transport,
+		Header:    make(http.Header),
+		resp:      &responseMetadata{header: make(http.Header)},
+	}
+}
+
+// forCall is a copy of md for a single call, so each call of a
+// request has its own Principal.
+func (md *Metadata) forCall() *Metadata {
+	c := *md
+	return &c
+}
+
+// SetHeader sets a header of the response. It is ignored when
+// there is no response to set it on, e.g. on a WebSocket, or
+// once a stream has started.
+func (md *Metadata) SetHeader(key, value string) {
+	md.resp.mu.Lock()
+	defer md.resp.mu.Unlock()
+	md.resp.header.Set(key, value)
+}
+
+// AddHeader adds a header to the response, see SetHeader.
+func (md *Metadata) AddHeader(key, value string) {
+	md.resp.mu.Lock()
+	defer md.resp.mu.Unlock()
+	md.resp.header.Add(key, value)
+}
+
+// SetCookie adds a Set-Cookie header to the response, see SetHeader.
+func (md *Metadata) SetCookie(cookie *http.Cookie) {
+	md.resp.mu.Lock()
+	defer md.resp.mu.Unlock()
+	md.resp.cookies = append(md.resp.cookies, cookie)
+}
+
+// responseHeader is what the calls set with SetHeader and SetCookie.
+func (md *Metadata) responseHeader() http.Header {
+	md.resp.mu.Lock()
+	defer md.resp.mu.Unlock()
+	header := md.resp.header.Clone()
+	for _, cookie := range md.resp.cookies {
+		if v := cookie.String(); v != "" {
+			header.Add("Set-Cookie", v)
+		}
+	}
+	return header
+}
+
+func fiberMetadata(c *fiber.Ctx, transport string) *Metadata {
+	md := newMetadata(transport)
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		md.Header.Add(string(key), string(value))
+	})
+	md.RemoteAddr = c.IP()
+	md.RequestID = c.GetRespHeader(fiber.HeaderXRequestID, c.Get(fiber.HeaderXRequestID))
+	return md
+}
+
+// fiberContext is the context of a call served by c. Unlike
+// c.Context(), it is canceled when the server shuts down, and
+// must be canceled once the call is done.
+func fiberContext(c *fiber.Ctx, transport string) (context.Context, *Metadata, context.CancelFunc) {
+	md := fiberMetadata(c, transport)
+	ctx, cancel := context.WithCancel(context.WithValue(c.UserContext(), metadataKey{}, md))
+	shutdown := c.Context().Done()
+	go func() {
+		select {
+		case <-shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, md, cancel
+}
+
+// setFiberHeader sends the headers the calls of md set.
+func setFiberHeader(c *fiber.Ctx, md *Metadata) {
+	for key, values := range md.responseHeader() {
+		c.Response().Header.Del(key)
+		for _, v := range values {
+			c.Append(key, v)
+		}
+	}
+}
+
+func httpMetadata(w http.ResponseWriter, r *http.Request, transport string) *Metadata {
+	md := newMetadata(transport)
+	md.Header = r.Header.Clone()
+	md.RemoteAddr = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		md.RemoteAddr = host
+	}
+	md.RequestID = w.Header().Get("X-Request-Id")
+	if md.RequestID == "" {
+		md.RequestID = r.Header.Get("X-Request-Id")
+	}
+	return md
+}
+
+// httpContext is the context of a call served on w and r.
+func httpContext(w http.ResponseWriter, r *http.Request, transport string) (context.Context, *Metadata) {
+	md := httpMetadata(w, r, transport)
+	return context.WithValue(r.Context(), metadataKey{}, md), md
+}
+
+// setHTTPHeader sends the headers the calls of md set.
+func setHTTPHeader(w http.ResponseWriter, md *Metadata) {
+	for key, values := range md.responseHeader() {
+		w.Header()[key] = values
+	}
+}
@@ -0,0 +1,248 @@
+package fit
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPI is an OpenAPI 3.1 document.
+// https://spec.openapis.org/oas/v3.1.0
+type OpenAPI struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       OpenAPIInfo          `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type PathItem struct {
+	Get  *Operation `json:"get,omitempty"`
+	Post *Operation `json:"post,omitempty"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Components struct {
+	Schemas   map[string]*Schema   `json:"schemas,omitempty"`
+	Responses map[string]*Response `json:"responses,omitempty"`
+}
+
+// YAML encodes the document as YAML.
+func (d *OpenAPI) YAML() ([]byte, error) {
+	// round trip through json so the json tags are honored.
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// WithVersion is the version of the API in its OpenAPI document,
+// 1.0.0 by default.
+func WithVersion(version string) Option {
+	return func(r *RPC) {
+		r.version = version
+	}
+}
+
+// Responds documents the code a method answers with when it
+// succeeds, e.g. codes.Created, instead of codes.OK.
+//
+//	fit.WithMethod("Create", fit.Responds(codes.Created))
+func Responds(code codes.Code) MethodOption {
+	return func(m *Method) {
+		m.responds = code
+	}
+}
+
+// OpenAPI describes every method of the RPC as a POST operation.
+//
+//	GET /<type>/openapi.json
+//	GET /<type>/openapi.yaml
+func (s *RPC) OpenAPI() *OpenAPI {
+	g := newSchemaGenerator("#/components/schemas/")
+	errorBody := g.schemaOf(reflect.TypeOf(ErrorBody{}))
+	doc := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info: OpenAPIInfo{
+			Title:       s.Name(),
+			Description: s.doc.doc(),
+			Version:     s.version,
+		},
+		Paths: make(map[string]*PathItem),
+		Components: &Components{
+			Schemas: g.defs,
+			Responses: map[string]*Response{
+				"BadRequest": errorResponse("the body failed to decode or validate", errorBody),
+				"Error":      errorResponse("the method returned a non 2xx status", errorBody),
+			},
+		},
+	}
+
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		m := s.methods[name]
+		methodDoc := m.doc.doc()
+		responses := map[string]*Response{
+			"400":     {Ref: "#/components/responses/BadRequest"},
+			"default": {Ref: "#/components/responses/Error"},
+		}
+		code := codes.OK
+		if m.responds != 0 {
+			code = m.responds
+		}
+		switch {
+		case m.stream != unary:
+			responses["200"] = streamResponse(m.responseSchema(g), errorBody)
+		case m.outType == nil:
+			if code == codes.OK {
+				code = codes.NoContent
+			}
+			responses[strconv.Itoa(int(code))] = &Response{Description: http.StatusText(int(code))}
+		default:
+			responses[strconv.Itoa(int(code))] = &Response{
+				Description: http.StatusText(int(code)),
+				Content:     s.mediaTypes(m.responseSchema(g)),
+			}
+		}
+		var headers []Parameter
+		if m.idempotency != nil && m.stream == unary {
+			maxLength := maxIdempotencyKeyLength
+			headers = append(headers, Parameter{
+				Name:   IdempotencyKeyHeader,
+				In:     "header",
+				Schema: &Schema{Type: SchemaType{"string"}, MaxLength: &maxLength},
+			})
+		}
+		doc.Paths["/"+s.Name()+"/"+name] = &PathItem{
+			Post: &Operation{
+				OperationID: name,
+				Summary:     summary(methodDoc),
+				Description: methodDoc,
+				Tags:        []string{s.Name()},
+				Parameters:  headers,
+				RequestBody: &RequestBody{
+					Required: m.inKind != inNone,
+					Content:  s.mediaTypes(g.schemaOf(m.inType.Elem())),
+				},
+				Responses: responses,
+			},
+		}
+		if m.get {
+			getPath := "/" + s.Name() + "/" + name
+			if m.getPath != "" {
+				getPath += "/" + m.getPath
+			}
+			item, ok := doc.Paths[getPath]
+			if !ok {
+				item = &PathItem{}
+				doc.Paths[getPath] = item
+			}
+			item.Get = &Operation{
+				OperationID: name + "_get",
+				Summary:     summary(methodDoc),
+				Description: methodDoc,
+				Tags:        []string{s.Name()},
+				Parameters:  append(parameters(g, m.inType.Elem()), headers...),
+				Responses:   responses,
+			}
+		}
+	}
+	return doc
+}
+
+// parameters describes the fields of t bound from a GET request.
+func parameters(g *schemaGenerator, t reflect.Type) []Parameter {
+	var params []Parameter
+	for _, field := range bindFields(t) {
+		params = append(params, Parameter{
+			Name:     field.name,
+			In:       field.in,
+			Required: field.in == "path",
+			Schema:   g.schemaOf(field.Type),
+		})
+	}
+	return params
+}
+
+// streamResponse describes the OUTPUT of a streaming method,
+// whose every event or line is an item of schema.
+func streamResponse(schema, errorBody *Schema) *Response {
+	return &Response{
+		Description: "a stream of OUTPUT, see https://html.spec.whatwg.org/multipage/server-sent-events.html and https://github.com/ndjson/ndjson-spec",
+		Content: map[string]MediaType{
+			"text/event-stream":    {Schema: &Schema{Type: SchemaType{"string"}}},
+			"application/x-ndjson": {Schema: &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{"result": schema, "error": errorBody}}},
+		},
+	}
+}
+
+// mediaTypes describes schema in every registered codec.
+func (s *RPC) mediaTypes(schema *Schema) map[string]MediaType {
+	content := make(map[string]MediaType)
+	for _, codec := range s.codecs {
+		content[codec.ContentType()] = MediaType{Schema: schema}
+	}
+	return content
+}
+
+// errorResponse is the ErrorBody written for a status.Status
+// with a code >= 300.
+func errorResponse(description string, schema *Schema) *Response {
+	return &Response{
+		Description: description,
+		Content: map[string]MediaType{
+			"application/json": {Schema: schema},
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package fit
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+type openAPIService struct{}
+
+type openAPIThing struct {
+	Name string `json:"name"`
+}
+
+func (openAPIService) Create(ctx context.Context, in *openAPIThing) (*openAPIThing, status.Status) {
+	return in, status.Created
+}
+
+func (openAPIService) Get(ctx context.Context, in *openAPIThing) (*openAPIThing, status.Status) {
+	return in, status.OK
+}
+
+func (openAPIService) Delete(ctx context.Context, in *openAPIThing) status.Status {
+	return status.OK
+}
+
+func (openAPIService) Enqueue(ctx context.Context, in *openAPIThing) status.Status {
+	return status.New(codes.Accepted, "queued")
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	rpc := NewRPC(openAPIService{},
+		WithMethod("Create", Responds(codes.Created)),
+		WithMethod("Enqueue", Responds(codes.Accepted)),
+	)
+	doc := rpc.OpenAPI()
+	tests := []struct {
+		method string
+		want   []string
+	}{
+		{"Create", []string{"201", "400", "default"}},
+		{"Get", []string{"200", "400", "default"}},
+		{"Delete", []string{"204", "400", "default"}},
+		{"Enqueue", []string{"202", "400", "default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			var got []string
+			for code := range doc.Paths["/"+rpc.Name()+"/"+tt.method].Post.Responses {
+				got = append(got, code)
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got responses %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got responses %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAPIVersion(t *testing.T) {
+	if got := NewRPC(openAPIService{}).OpenAPI().Info.Version; got != "1.0.0" {
+		t.Errorf("got version %q by default, want 1.0.0", got)
+	}
+	if got := NewRPC(openAPIService{}, WithVersion("2.3.0")).OpenAPI().Info.Version; got != "2.3.0" {
+		t.Errorf("got version %q, want 2.3.0", got)
+	}
+}
@@ -0,0 +1,84 @@
+package fit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// Panic is a panic recovered from a method or interceptor. It is
+// logged, reported to WithPanicHandler, and answered with
+// codes.Internal.
+type Panic struct {
+	// ID correlates the panic with the codes.Internal status
+	// sent to the client.
+	ID    string
+	Info  *CallInfo
+	In    any
+	Value any
+	Stack []byte
+}
+
+// PanicHandler is told of every recovered Panic, e.g. to forward
+// it to a crash reporter. It runs before the client is answered.
+type PanicHandler func(ctx context.Context, p *Panic)
+
+// WithPanicHandler is told of panics, after they are logged.
+//
+//	fit.WithPanicHandler(func(ctx context.Context, p *fit.Panic) {
+//		sentry.CaptureException(fmt.Errorf("%v: %v", p.Info.FullMethod(), p.Value))
+//	})
+func WithPanicHandler(handler PanicHandler) Option {
+	return func(r *RPC) {
+		r.panicHandler = handler
+	}
+}
+
+// recovered logs a panic, and turns it into a status whose
+// message and details carry its id, but none of its value.
+func (s *RPC) recovered(ctx context.Context, info *CallInfo, in any, v any) status.Status {
+	p := &Panic{
+		ID:    newPanicID(),
+		Info:  info,
+		In:    in,
+		Value: v,
+		Stack: debug.Stack(),
+	}
+
+	input, err := json.Marshal(in)
+	if err != nil {
+		input = []byte(fmt.Sprintf("%+v", in))
+	}
+	log.Printf("fit: %v panicked: %v\nid: %v\nrequest_id: %v\ninput: %s\n%s",
+		info.FullMethod(), v, p.ID, MetadataFromContext(ctx).RequestID, input, p.Stack)
+
+	if s.panicHandler != nil {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					log.Printf("fit: panic handler panicked: %v", v)
+				}
+			}()
+			s.panicHandler(ctx, p)
+		}()
+	}
+
+	return status.Newf(codes.Internal, "internal error, id %v", p.ID).WithDetails(status.ErrorInfo{
+		Reason:   "PANIC",
+		Domain:   "fit",
+		Metadata: map[string]string{"id": p.ID},
+	})
+}
+
+func newPanicID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
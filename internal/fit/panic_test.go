@@ -0,0 +1,133 @@
+package fit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+type panicService struct{}
+
+type panicRequest struct {
+	Message string `json:"message"`
+}
+
+func (panicService) Panic(ctx context.Context, in *panicRequest) (*panicRequest, status.Status) {
+	panic("boom")
+}
+
+func (panicService) Echo(ctx context.Context, in *panicRequest) (*panicRequest, status.Status) {
+	return in, status.OK
+}
+
+func TestPanicRecovery(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var (
+		mu     sync.Mutex
+		panics []*Panic
+	)
+	rpc := NewRPC(panicService{},
+		WithPanicHandler(func(ctx context.Context, p *Panic) {
+			mu.Lock()
+			defer mu.Unlock()
+			panics = append(panics, p)
+		}),
+		WithInterceptors(func(ctx context.Context, info *CallInfo, in any, next Invoker) (any, status.Status) {
+			if in.(*panicRequest).Message == "interceptor" {
+				panic("boom")
+			}
+			return next(ctx, in)
+		}),
+	)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	rpc.MountFiberApp(app)
+	netHttp := rpc.NewNetHttpHandler()
+
+	transports := []struct {
+		name string
+		do   func(r *http.Request) (*http.Response, error)
+	}{
+		{"fiber", func(r *http.Request) (*http.Response, error) {
+			return app.Test(r, -1)
+		}},
+		{"net/http", func(r *http.Request) (*http.Response, error) {
+			w := httptest.NewRecorder()
+			netHttp(w, r)
+			return w.Result(), nil
+		}},
+	}
+	tests := []struct {
+		name, method, body string
+	}{
+		{"method", "Panic", `{"message":"hi"}`},
+		{"interceptor", "Echo", `{"message":"interceptor"}`},
+	}
+	for _, transport := range transports {
+		for _, tt := range tests {
+			t.Run(transport.name+"/"+tt.name, func(t *testing.T) {
+				mu.Lock()
+				panics = nil
+				mu.Unlock()
+
+				r := httptest.NewRequest(http.MethodPost, "/"+rpc.Name()+"/"+tt.method, strings.NewReader(tt.body))
+				r.Header.Set("Content-Type", "application/json")
+				resp, err := transport.do(r)
+				if err != nil {
+					t.Fatalf("failed to call: %v", err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusInternalServerError {
+					t.Errorf("got code %v, want 500", resp.StatusCode)
+				}
+				if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+					t.Errorf("got Content-Type %q, want application/json", contentType)
+				}
+
+				var body struct {
+					Code    int    `json:"code"`
+					Message string `json:"message"`
+					Details []struct {
+						Type     string            `json:"@type"`
+						Reason   string            `json:"reason"`
+						Domain   string            `json:"domain"`
+						Metadata map[string]string `json:"metadata"`
+					} `json:"details"`
+				}
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatalf("failed to decode body: %v", err)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				if len(panics) != 1 {
+					t.Fatalf("got %v panics, want 1", len(panics))
+				}
+				p := panics[0]
+				if p.Value != "boom" || p.Info.FullMethod() != "/"+rpc.Name()+"/"+tt.method {
+					t.Errorf("got panic %v of %v", p.Value, p.Info.FullMethod())
+				}
+				if body.Code != 500 || body.Message != "internal error, id "+p.ID {
+					t.Errorf("got %v %q, want 500 with id %v", body.Code, body.Message, p.ID)
+				}
+				if len(body.Details) != 1 || body.Details[0].Type != "ErrorInfo" || body.Details[0].Reason != "PANIC" || body.Details[0].Metadata["id"] != p.ID {
+					t.Errorf("got details %+v, want an ErrorInfo of the panic", body.Details)
+				}
+				if strings.Contains(body.Message, "boom") {
+					t.Errorf("the message %q leaks the panic", body.Message)
+				}
+			})
+		}
+	}
+}
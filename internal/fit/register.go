@@ -0,0 +1,77 @@
+package fit
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"reflect"
+
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// Register adds a unary method called name to r, which is called
+// directly rather than by reflection. It must be registered before
+// r is mounted, and panics when name is taken or invalid, as that
+// is a programming error.
+//
+// The doc comment of fn describes the method, once cmd/fitdoc
+// generated it. see RegisterDocs.
+//
+//	rpc := fit.New("users.Service", fit.WithDefaultTimeout(10*time.Second))
+//	fit.Register(rpc, "Create", svc.Create)
+//	fit.Register(rpc, "GetByUUID", svc.GetByUUID, fit.Get("/{uuid}"))
+func Register[I, O any](r *RPC, name string, fn func(ctx context.Context, in *I) (*O, status.Status), opts ...MethodOption) {
+	m := newRegistered[I, O](r, name, unary)
+	m.invoke = func(ctx context.Context, in any) (any, status.Status) {
+		return fn(ctx, in.(*I))
+	}
+	m.doc = funcDocKey(fn)
+	r.register(m, opts)
+}
+
+// RegisterStream adds a streaming method called name to r, see
+// Register.
+//
+//	fit.RegisterStream(rpc, "Watch", svc.Watch, fit.Get(""))
+func RegisterStream[I, O any](r *RPC, name string, fn func(ctx context.Context, in *I, send func(*O) error) status.Status, opts ...MethodOption) {
+	m := newRegistered[I, O](r, name, sendStream)
+	m.send = func(ctx context.Context, in any, send func(out any) error) status.Status {
+		return fn(ctx, in.(*I), func(out *O) error {
+			return send(out)
+		})
+	}
+	m.doc = funcDocKey(fn)
+	r.register(m, opts)
+}
+
+func newRegistered[I, O any](r *RPC, name string, kind streamKind) *Method {
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		panic(fmt.Sprintf("fit: Register: %v is not a valid method name", name))
+	}
+	if _, ok := r.methods[name]; ok {
+		panic(fmt.Sprintf("fit: Register: %v already has a method %v", r.Name(), name))
+	}
+	inType, outType := reflect.TypeOf((*I)(nil)), reflect.TypeOf((*O)(nil))
+	if !isStructPointer(inType) || !isStructPointer(outType) {
+		panic(fmt.Sprintf("fit: Register: %v must take and return structs, not %v and %v", name, inType.Elem(), outType.Elem()))
+	}
+	return &Method{
+		In:      inType.Elem().Name(),
+		inType:  inType,
+		Out:     outType.Elem().Name(),
+		outType: outType,
+
+		name:   name,
+		stream: kind,
+		newIn: func() any {
+			return new(I)
+		},
+	}
+}
+
+func (r *RPC) register(m *Method, opts []MethodOption) {
+	for _, opt := range opts {
+		opt(m)
+	}
+	r.methods[m.name] = m
+}
@@ -0,0 +1,79 @@
+package fit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+type benchEcho struct{}
+
+type benchEchoRequest struct {
+	Message string `json:"message" validate:"required"`
+}
+
+type benchEchoResponse struct {
+	Message string `json:"message"`
+}
+
+func (benchEcho) Echo(ctx context.Context, in *benchEchoRequest) (*benchEchoResponse, status.Status) {
+	return &benchEchoResponse{Message: in.Message}, status.OK
+}
+
+// benchRPCs are benchEcho as found by NewRPC, and as added by
+// Register.
+func benchRPCs() []struct {
+	name string
+	rpc  *RPC
+} {
+	reflected := NewRPC(benchEcho{})
+	registered := New(reflected.Name())
+	Register(registered, "Echo", benchEcho{}.Echo)
+	return []struct {
+		name string
+		rpc  *RPC
+	}{
+		{"NewRPC", reflected},
+		{"Register", registered},
+	}
+}
+
+func BenchmarkInvoke(b *testing.B) {
+	for _, bm := range benchRPCs() {
+		rpc := bm.rpc
+		b.Run(bm.name, func(b *testing.B) {
+			invoke := rpc.methods["Echo"].invoke
+			ctx, in := context.Background(), &benchEchoRequest{Message: "hi"}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, st := invoke(ctx, in); st.Code >= 300 {
+					b.Fatalf("%v: %v", st.Code, st.Message)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNetHttpHandler(b *testing.B) {
+	for _, bm := range benchRPCs() {
+		rpc := bm.rpc
+		b.Run(bm.name, func(b *testing.B) {
+			handler := rpc.NewNetHttpHandler()
+			target := "/" + rpc.Name() + "/Echo"
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"message":"hi"}`))
+				r.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				handler(w, r)
+				if w.Code != http.StatusOK {
+					b.Fatalf("%v: %v", w.Code, w.Body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package fit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// route is an endpoint of an RPC, served the same way by every
+// transport.
+type route struct {
+	// path follows /<type>, or / on a Server, with {name}
+	// segments for parameters, e.g. /GetByUUID/{uuid}
+	path  string
+	verbs []string
+	fiber fiber.Handler
+	http  func(w http.ResponseWriter, r *http.Request, params map[string]string)
+}
+
+// routes are matched in order, so those of docs come before the
+// GET paths of methods.
+func (s *RPC) routes() []route {
+	routes := []route{
+		docRoute("/help", "application/json", func() ([]byte, error) { return json.Marshal(s.docsJSON()) }),
+		docRoute("/openapi.json", "application/json", func() ([]byte, error) { return json.Marshal(s.OpenAPI()) }),
+		docRoute("/openapi.yaml", "application/yaml", func() ([]byte, error) { return s.OpenAPI().YAML() }),
+		docRoute("/help/ui", "text/html; charset=utf-8", func() ([]byte, error) { return explorerHTML, nil }),
+		{
+			path:  "/_batch",
+			verbs: []string{http.MethodPost},
+			fiber: s.fiberBatch,
+			http:  func(w http.ResponseWriter, r *http.Request, _ map[string]string) { s.netHttpBatch(w, r) },
+		},
+		{
+			path:  "/_ws",
+			verbs: []string{http.MethodGet},
+			fiber: s.fiberWS,
+			http:  func(w http.ResponseWriter, r *http.Request, _ map[string]string) { s.netHttpWS(w, r) },
+		},
+	}
+
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		method := s.methods[name]
+		routes = append(routes, docRoute("/"+name+"/help", "application/json", func() ([]byte, error) { return json.Marshal(method.docsJSON()) }))
+	}
+	for _, name := range names {
+		method := s.methods[name]
+		call := route{
+			path:  "/" + name,
+			verbs: []string{http.MethodPost},
+			fiber: s.fiberUnary(method),
+			http:  s.netHttpUnary(method),
+		}
+		if method.stream != unary {
+			call.fiber, call.http = s.fiberStream(method), s.netHttpStream(method)
+		}
+		if method.get && method.getPath == "" {
+			call.verbs = append(call.verbs, http.MethodGet)
+		}
+		routes = append(routes, call)
+		if method.get && method.getPath != "" {
+			get := call
+			get.path += "/" + method.getPath
+			get.verbs = []string{http.MethodGet}
+			routes = append(routes, get)
+		}
+	}
+	return routes
+}
+
+// docRoute serves what encode returns on GET.
+func docRoute(path, contentType string, encode func() ([]byte, error)) route {
+	return route{
+		path:  path,
+		verbs: []string{http.MethodGet},
+		fiber: func(c *fiber.Ctx) error {
+			data, err := encode()
+			if err != nil {
+				return sendFiberError(c, status.Newf(codes.Internal, "failed to encode docs: %v", err))
+			}
+			c.Set(fiber.HeaderContentType, contentType)
+			return c.Send(data)
+		},
+		http: func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			data, err := encode()
+			if err != nil {
+				writeHTTPError(w, r, status.Newf(codes.Internal, "failed to encode docs: %v", err))
+				return
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Write(data)
+		},
+	}
+}
+
+// fiberPath is the path of rt in fiber syntax, e.g. /GetByUUID/:uuid
+func (rt route) fiberPath() string {
+	segs := strings.Split(strings.TrimPrefix(rt.path, "/"), "/")
+	for i, seg := range segs {
+		if name, ok := pathParam(seg); ok {
+			segs[i] = ":" + name
+		}
+	}
+	return "/" + strings.Join(segs, "/")
+}
+
+// match matches the segments of an escaped path against rt. Like
+// fiber, it ignores the case of the others.
+func (rt route) match(parts []string) (map[string]string, bool) {
+	segs := strings.Split(strings.TrimPrefix(rt.path, "/"), "/")
+	if len(parts) != len(segs) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, seg := range segs {
+		if name, ok := pathParam(seg); ok {
+			v, err := url.PathUnescape(parts[i])
+			if err != nil || v == "" {
+				return nil, false
+			}
+			params[name] = v
+		} else if !strings.EqualFold(seg, parts[i]) {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// allows is whether rt is served on verb. GET routes are served on
+// HEAD too.
+func (rt route) allows(verb string) bool {
+	for _, v := range rt.verbs {
+		if v == verb || (v == http.MethodGet && verb == http.MethodHead) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPath splits an escaped path after /<type> into segments,
+// ignoring a trailing slash as fiber does.
+func splitPath(p string) []string {
+	return strings.Split(strings.TrimPrefix(strings.TrimSuffix(p, "/"), "/"), "/")
+}
+
+// routeError is codes.MethodNotAllowed, with the verbs to set the
+// Allow header to, when routes serve parts on other verbs, and
+// codes.NotFound otherwise.
+func routeError(routes []route, parts []string, p string) (status.Status, string) {
+	var allow []string
+	for _, rt := range routes {
+		if _, ok := rt.match(parts); ok {
+			allow = append(allow, rt.verbs...)
+		}
+	}
+	if len(allow) == 0 {
+		return status.Newf(codes.NotFound, "not found: %v", p), ""
+	}
+	return status.Newf(codes.MethodNotAllowed, "method not allowed, use %v", strings.Join(allow, ", ")), strings.Join(allow, ", ")
+}
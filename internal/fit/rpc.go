@@ -2,35 +2,62 @@ package fit
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
 	"path"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
+	"github.com/hyqe/ribose/internal/fit/codes"
 	"github.com/hyqe/ribose/internal/fit/status"
 )
 
 type RPC struct {
 	methods map[string]*Method
-	ptr     reflect.Value
+	name    string
+	doc     docKey
+	// skipped are the exported methods that aren't served, and why.
+	skipped      map[string]error
+	strict       bool
+	interceptors []Interceptor
+	codecs       map[string]Codec
+	// defaultTimeout bounds unary methods without a timeout.
+	defaultTimeout time.Duration
+	panicHandler   PanicHandler
+	wsOrigins      map[string]bool
+	// wsMaxCalls and wsMaxMessageSize limit each WebSocket.
+	wsMaxCalls       int
+	wsMaxMessageSize int64
+
+	maxBatchSize     int
+	batchConcurrency int
+	// version is the version of the OpenAPI document.
+	version string
+
 	*validator.Validate
 }
 
+// Option configures an RPC.
+type Option func(*RPC)
+
 // NewRPC builds an RPC with from an instance of a type and
 // its methods.
 //
-// The exported methods must have the following signature.
+// The exported methods must have one of the following signatures.
 // Where INPUT is a pointer to your input type, and OUTPUT
 // is value your method sends back to the client.
 //
 //	fn(ctx context.Context, in *INPUT) (*OUTPUT, status.Status)
+//	fn(ctx context.Context, in *INPUT, send func(*OUTPUT) error) status.Status
+//	fn(ctx context.Context, in *INPUT) (<-chan *OUTPUT, status.Status)
+//
+// The last two stream their OUTPUT. Methods with none of them are
+// skipped, unless WithStrict is given.
 //
 // INPUT is validated before being passed to it method. see
 // https://pkg.go.dev/github.com/go-playground/validator/v10
@@ -42,292 +69,584 @@ type RPC struct {
 // Docs endpoints are created for each method.
 //
 //	GET /<type>/help // gets list of methods
+//	GET /<type>/help/ui // explores and calls the methods in a browser
 //	GET /<type>/<method>/help // gets INPUT/OUTPUT
-func NewRPC(ptr any) *RPC {
+//	GET /<type>/openapi.json // gets an OpenAPI 3.1 document
+//	GET /<type>/openapi.yaml
+func NewRPC(ptr any, opts ...Option) *RPC {
 	reflectVal := reflect.ValueOf(ptr)
-	return &RPC{
-		methods:  parseMethods(reflectVal),
-		ptr:      reflectVal,
-		Validate: validator.New(),
+	methods, skipped := parseMethods(reflectVal)
+	r := newRPC(serviceName(reflectVal.Type()), methods, skipped, opts)
+	r.doc = typeDocKey(reflectVal.Type(), "")
+	if r.strict && len(skipped) > 0 {
+		panic(fmt.Sprintf("fit: NewRPC: %v", r.skippedError()))
+	}
+	return r
+}
+
+// New builds an RPC served under name, e.g. users.Service,
+// without any methods. see Register.
+func New(name string, opts ...Option) *RPC {
+	return newRPC(name, make(map[string]*Method), nil, opts)
+}
+
+func newRPC(name string, methods map[string]*Method, skipped map[string]error, opts []Option) *RPC {
+	r := &RPC{
+		methods:   methods,
+		skipped:   skipped,
+		name:      name,
+		Validate:  validator.New(),
+		codecs:    defaultCodecs(),
+		wsOrigins: make(map[string]bool),
+
+		maxBatchSize:     DefaultMaxBatchSize,
+		batchConcurrency: 1,
+		version:          "1.0.0",
+		wsMaxCalls:       DefaultMaxWebSocketCalls,
+		wsMaxMessageSize: DefaultMaxWebSocketMessageSize,
+	}
+	r.Validate.RegisterTagNameFunc(jsonFieldName)
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// WithStrict makes NewRPC panic when exported methods of the
+// type are skipped, saying why for each of them, instead of
+// leaving them unserved.
+//
+//	fit.NewRPC(svc, fit.WithStrict())
+//	// panic: fit: NewRPC: users.Service skips methods:
+//	//	Ping: in is string, want a struct or a pointer to one
+func WithStrict() Option {
+	return func(r *RPC) {
+		r.strict = true
+	}
+}
+
+// skippedError lists the skipped methods, and why.
+func (r *RPC) skippedError() error {
+	names := make([]string, 0, len(r.skipped))
+	for name := range r.skipped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v skips methods:", r.Name())
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n\t%v: %v", name, r.skipped[name])
 	}
+	return errors.New(b.String())
 }
 
 func (r *RPC) Name() string {
-	v := reflect.Indirect(r.ptr)
-	typName := v.Type().Name()
-	pkgName := v.Type().PkgPath()
-	return path.Base(pkgName) + "." + typName
+	return r.name
 }
 
-func parseMethods(v reflect.Value) map[string]*Method {
-	methods := make(map[string]*Method)
+// ServiceName is the name an RPC built from svc is served under,
+// e.g. users.Service
+func ServiceName(svc any) string {
+	return serviceName(reflect.TypeOf(svc))
+}
+
+func serviceName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// parseMethods finds the methods of v, and why the other exported
+// methods were skipped.
+func parseMethods(v reflect.Value) (methods map[string]*Method, skipped map[string]error) {
+	methods = make(map[string]*Method)
+	skipped = make(map[string]error)
 	methodNames := ListExportedMethodNames(v.Type())
 	for _, methodName := range methodNames {
-		if method, ok := newMethod(v, methodName); ok {
-			methods[methodName] = &method
+		method, err := newMethod(v, methodName)
+		if err != nil {
+			skipped[methodName] = err
+			continue
+		}
+		methods[methodName] = &method
+	}
+	return methods, skipped
+}
+
+// call validates in and invokes method through the interceptors.
+func (s *RPC) call(ctx context.Context, method *Method, in any) (any, status.Status) {
+	if method.stream != unary {
+		return nil, status.Newf(codes.BadRequest, "%v streams, it must be called on its own endpoint", method.name)
+	}
+	return s.intercept(ctx, method, in, method.invoke)
+}
+
+// intercept validates in and calls invoke through the interceptors,
+// recovering from their panics.
+func (s *RPC) intercept(ctx context.Context, method *Method, in any, invoke Invoker) (out any, st status.Status) {
+	info := &CallInfo{
+		Service: s.Name(),
+		Method:  method.name,
+		Stream:  method.stream != unary,
+	}
+	ctx = context.WithValue(ctx, metadataKey{}, MetadataFromContext(ctx).forCall())
+	ctx, cancel, st := s.withDeadline(ctx, method)
+	defer cancel()
+	if st.Code >= 300 {
+		return nil, st
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			out, st = nil, s.recovered(ctx, info, in, v)
+		}
+	}()
+	out, st = chain(s.interceptors, info, func(ctx context.Context, in any) (any, status.Status) {
+		if err := s.Validate.Struct(in); err != nil {
+			return nil, validationStatus(err)
 		}
+		return s.idempotent(ctx, info, method, in, invoke)
+	})(ctx, in)
+	if err := ctx.Err(); err != nil && st.Code >= 300 {
+		return nil, contextStatus(err)
 	}
-	return methods
+	return out, st
 }
 
-func (s *RPC) docsJSON() any {
+// decodeIn decodes the INPUT of method from body, with the codec
+// of contentType.
+func (s *RPC) decodeIn(method *Method, contentType string, body []byte) (any, Codec, status.Status) {
+	inCodec, _, st := s.negotiate(contentType, "")
+	if st.Code >= 300 {
+		return nil, nil, st
+	}
+	in := method.newIn()
+	// methods without INPUT can be called without a body.
+	if len(body) == 0 && method.inKind == inNone {
+		return in, inCodec, status.OK
+	}
+	if err := inCodec.Unmarshal(body, in); err != nil {
+		return nil, nil, status.Newf(codes.BadRequest, "failed to decode body: %v", err)
+	}
+	return in, inCodec, status.OK
+}
+
+// reply calls method, and encodes its OUTPUT with the codec
+// picked by the Accept header, defaulting to inCodec.
+func (s *RPC) reply(ctx context.Context, method *Method, in any, inCodec Codec, accept string) (respType string, data []byte, st status.Status) {
+	outCodec, ok := s.acceptable(accept, inCodec)
+	if !ok {
+		return "", nil, status.Newf(codes.NotAcceptable, "none of the accepted types are supported: %v", accept)
+	}
+	out, st := s.call(ctx, method, in)
+	if st.Code >= 300 {
+		return "", nil, st
+	}
+	data, err := outCodec.Marshal(out)
+	if err != nil {
+		return "", nil, status.Newf(codes.Internal, "failed to encode response: %v", err)
+	}
+	return outCodec.ContentType(), data, st
+}
+
+// ServiceDocs is served at GET /<type>/help
+type ServiceDocs struct {
+	Description string   `json:"description,omitempty"`
+	Methods     []string `json:"methods"`
+}
+
+func (s *RPC) docsJSON() ServiceDocs {
 	methods := make([]string, 0, len(s.methods))
 	for _, m := range s.methods {
 		methods = append(methods, m.name)
 	}
-	return map[string]any{
-		"methods": methods,
+	sort.Strings(methods)
+	return ServiceDocs{
+		Description: s.doc.doc(),
+		Methods:     methods,
 	}
 }
 
+// MountFiberApp serves the RPC at /<type> on app.
 func (s *RPC) MountFiberApp(app *fiber.App) fiber.Router {
 	sub := fiber.New()
-
-	sub.Get("/help", func(c *fiber.Ctx) error {
-		return c.JSON(s.docsJSON())
+	routes := s.routes()
+	for _, rt := range routes {
+		for _, verb := range rt.verbs {
+			sub.Add(verb, rt.fiberPath(), rt.fiber)
+			if verb == fiber.MethodGet {
+				sub.Add(fiber.MethodHead, rt.fiberPath(), rt.fiber)
+			}
+		}
+	}
+	// answers what no route did, the same as NewNetHttpHandler.
+	sub.Use(func(c *fiber.Ctx) error {
+		p, rest := c.Path(), c.Path()
+		// the route of Use is the prefix the app is mounted at.
+		if prefix := c.Route().Path; len(rest) >= len(prefix) {
+			rest = rest[len(prefix):]
+		}
+		st, allow := routeError(routes, splitPath(rest), p)
+		if allow != "" {
+			c.Set(fiber.HeaderAllow, allow)
+		}
+		return sendFiberError(c, st)
 	})
+	return app.Mount("/"+s.Name(), sub)
+}
 
-	for _, m := range s.methods {
-		func(method *Method) {
-			subPath, _ := url.JoinPath("/", method.name)
-			subHelp, _ := url.JoinPath("/", method.name, "help")
-			sub.Get(path.Join(subHelp), func(c *fiber.Ctx) error {
-				return c.JSON(method.docsJSON())
-			})
-			sub.Post(subPath, func(c *fiber.Ctx) error {
-				in := method.NewIn().Interface()
-				err := c.BodyParser(in)
-				if err != nil {
-					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed to decode body: %v", err))
-				}
-				err = s.Validate.Struct(in)
-				if err != nil {
-					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("validation failed: %v", err))
-				}
-				out, status := method.Invoke(c.Context(), in)
-				if status.Code >= 300 {
-					return fiber.NewError(int(status.Code), status.Message)
-				}
-				err = c.JSON(out)
-				if err != nil {
-					return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("failed to encode response: %v", err))
-				}
-				return c.SendStatus(int(status.Code))
-			})
-		}(m)
+func (s *RPC) fiberUnary(method *Method) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		in, inCodec, st := s.fiberIn(c, method)
+		if st.Code >= 300 {
+			return sendFiberError(c, st)
+		}
+		ctx, md, cancel := fiberContext(c, TransportHTTP)
+		defer cancel()
+		contentType, data, st := s.reply(ctx, method, in, inCodec, c.Get(fiber.HeaderAccept))
+		setFiberHeader(c, md)
+		if st.Code >= 300 {
+			return sendFiberError(c, st)
+		}
+		if st.Code == codes.NoContent {
+			return c.SendStatus(int(st.Code))
+		}
+		c.Set(fiber.HeaderContentType, contentType)
+		return c.Status(int(st.Code)).Send(data)
 	}
+}
 
-	return app.Mount("/"+s.Name(), sub)
+func (s *RPC) netHttpUnary(method *Method) func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		in, inCodec, st := s.netHttpIn(w, r, method, params)
+		if st.Code >= 300 {
+			writeHTTPError(w, r, st)
+			return
+		}
+		ctx, md := httpContext(w, r, TransportHTTP)
+		contentType, data, st := s.reply(ctx, method, in, inCodec, r.Header.Get("Accept"))
+		setHTTPHeader(w, md)
+		if st.Code >= 300 {
+			writeHTTPError(w, r, st)
+			return
+		}
+		if st.Code == codes.NoContent {
+			w.WriteHeader(int(st.Code))
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(int(st.Code))
+		w.Write(data)
+	}
 }
 
+// NewNetHttpHandler serves the RPC at /<type>, with the same
+// routes, docs and errors as MountFiberApp.
 func (s *RPC) NewNetHttpHandler() http.HandlerFunc {
-	mux := http.NewServeMux()
-	for _, m := range s.methods {
-		func(method *Method) {
-			fullPath, _ := url.JoinPath("/", s.Name(), method.name)
-			mux.HandleFunc(fullPath, func(w http.ResponseWriter, r *http.Request) {
-				in := method.NewIn().Interface()
-				err := json.NewDecoder(r.Body).Decode(in)
-				if err != nil {
-					http.Error(w, fmt.Sprintf("failed to decode body as json: %v", err), http.StatusBadRequest)
-					return
-				}
-				err = s.Validate.Struct(in)
-				if err != nil {
-					http.Error(w, fmt.Sprintf("validation failed: %v", err), http.StatusBadRequest)
-					return
-				}
-				out, status := method.Invoke(r.Context(), in)
-				if status.Code >= 300 {
-					http.Error(w, status.Message, int(status.Code))
-					return
-				}
-				w.WriteHeader(int(status.Code))
-				json.NewEncoder(w).Encode(out)
-			})
-		}(m)
-	}
+	routes := s.routes()
+	prefix := "/" + s.Name()
 	return func(w http.ResponseWriter, r *http.Request) {
-		mux.ServeHTTP(w, r)
+		p := r.URL.EscapedPath()
+		if len(p) < len(prefix) || !strings.EqualFold(p[:len(prefix)], prefix) || (len(p) > len(prefix) && p[len(prefix)] != '/') {
+			writeHTTPError(w, r, status.Newf(codes.NotFound, "not found: %v", p))
+			return
+		}
+		parts := splitPath(p[len(prefix):])
+		for _, rt := range routes {
+			if params, ok := rt.match(parts); ok && rt.allows(r.Method) {
+				rt.http(w, r, params)
+				return
+			}
+		}
+		st, allow := routeError(routes, parts, p)
+		if allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		writeHTTPError(w, r, st)
 	}
 }
 
 type Method struct {
-	In      string
-	inType  reflect.Type
-	Out     string
+	In string
+	// inType is a pointer to the INPUT struct, however the
+	// method takes it.
+	inType reflect.Type
+	inKind inArg
+	Out    string
+	// outType is nil when the method has no OUTPUT.
 	outType reflect.Type
 
-	svc  reflect.Value
-	name string
-	fn   reflect.Value
+	svc    reflect.Value
+	name   string
+	doc    docKey
+	fn     reflect.Value
+	stream streamKind
+	// newIn, invoke and send call the method, by reflection
+	// unless it was registered. see Register.
+	newIn  func() any
+	invoke Invoker
+	send   func(ctx context.Context, in any, send func(out any) error) status.Status
+	recv   func(ctx context.Context, in any) (reflect.Value, status.Status)
+	// timeout bounds each call, overriding the default.
+	timeout time.Duration
+	// idempotency keeps the responses of calls with an
+	// Idempotency-Key. see Idempotent.
+	idempotency IdempotencyStore
+	// responds is the code of a successful call in the docs,
+	// or 0 for codes.OK. see Responds.
+	responds codes.Code
+
+	// get is set when the method is served on GET too, at
+	// getPath after its own. see Get.
+	get     bool
+	getPath string
 }
 
-// NewMethod is looking for a method with this signature:
+// NewMethod is looking for a method with one of these signatures:
 //
 //	fn[I, O any](ctx context.Context, in I) (O, status.Status)
-func newMethod(svc reflect.Value, methodName string) (method Method, ok bool) {
+//	fn[I, O any](ctx context.Context, in I, send func(O) error) status.Status
+//	fn[I, O any](ctx context.Context, in I) (<-chan O, status.Status)
+//
+// in can be left out, and so can O when the method doesn't stream,
+// which answers codes.OK with codes.NoContent instead. I and O
+// are structs or pointers to them, and O can be a slice when it
+// isn't streamed.
+//
+// status.Status can be an error instead, which is a status.Status
+// in its chain or else codes.Internal. see status.FromError.
+//
+// Channels are closed by the method when it is done. Like send, it
+// should stop once ctx is done.
+//
+// err says why a method doesn't have any of them.
+func newMethod(svc reflect.Value, methodName string) (method Method, err error) {
 	parentReflectedType := svc.Type()
 	reflectedMethod, _ := parentReflectedType.MethodByName(methodName)
-	// (ctx context.Context, in I)
-	NumIn := reflectedMethod.Type.NumIn()
-	if NumIn != 3 {
-		return
+	fnType := reflectedMethod.Type
+	// the arguments after the receiver.
+	params := make([]reflect.Type, fnType.NumIn()-1)
+	for i := range params {
+		params[i] = fnType.In(i + 1)
 	}
 
 	// first arg is a context
 	reflectContext := reflect.TypeOf((*context.Context)(nil)).Elem()
-	if !reflectedMethod.Type.In(1).Implements(reflectContext) {
-		return
+	if len(params) == 0 {
+		return method, errors.New("takes no arguments, want ctx first")
 	}
-
-	// second arg is a *struct
-	if reflect.Pointer != reflectedMethod.Type.In(2).Kind() {
-		return
+	if !params[0].Implements(reflectContext) {
+		return method, fmt.Errorf("ctx is %v, want context.Context", params[0])
 	}
-	if reflect.Struct != reflectedMethod.Type.In(2).Elem().Kind() {
-		return
+	params = params[1:]
+
+	// then maybe a struct, or a pointer to one
+	inType, inKind := emptyInputType, inNone
+	if len(params) > 0 && params[0].Kind() != reflect.Func {
+		switch {
+		case isStructPointer(params[0]):
+			inType, inKind = params[0], inPointer
+		case params[0].Kind() == reflect.Struct:
+			inType, inKind = reflect.PointerTo(params[0]), inValue
+		default:
+			return method, fmt.Errorf("in is %v, want a struct or a pointer to one", params[0])
+		}
+		params = params[1:]
 	}
 
-	// (O, status.Status)
-	NumOut := reflectedMethod.Type.NumOut()
-	if NumOut != 2 {
-		return
+	// then maybe a func(O) error
+	var sendType reflect.Type
+	if len(params) > 0 {
+		sendType = params[0]
+		if sendType.Kind() != reflect.Func || sendType.NumIn() != 1 || sendType.NumOut() != 1 || sendType.Out(0) != errorType {
+			return method, fmt.Errorf("send is %v, want func(OUTPUT) error", sendType)
+		}
+		params = params[1:]
+	}
+	if len(params) > 0 {
+		return method, fmt.Errorf("takes %v arguments, want (ctx, in, send) at most", fnType.NumIn()-1)
 	}
 
-	// first return is a *struct
-	if reflect.Pointer != reflectedMethod.Type.Out(0).Kind() {
-		return
+	// returning status.Status or error last
+	numOut := fnType.NumOut()
+	if numOut == 0 || (fnType.Out(numOut-1) != statusType && fnType.Out(numOut-1) != errorType) {
+		return method, fmt.Errorf("returns %v, want status.Status or error last", results(fnType))
 	}
-	if reflect.Struct != reflectedMethod.Type.Out(0).Elem().Kind() {
-		return
+
+	var (
+		outType reflect.Type
+		kind    streamKind
+	)
+	switch {
+	case sendType != nil:
+		if numOut != 1 {
+			return method, fmt.Errorf("returns %v, want only status.Status or error when streaming", results(fnType))
+		}
+		outType = sendType.In(0)
+		kind = sendStream
+	case numOut == 2:
+		outType = fnType.Out(0)
+		if outType.Kind() == reflect.Chan && outType.ChanDir()&reflect.RecvDir != 0 {
+			outType = outType.Elem()
+			kind = chanStream
+		}
+	case numOut > 2:
+		return method, fmt.Errorf("returns %v, want (OUTPUT, status.Status) at most", results(fnType))
 	}
 
-	// second return is a status.Status
-	if reflect.TypeOf(status.Status{}) != reflectedMethod.Type.Out(1) {
-		return
+	// the OUTPUT is a struct, a pointer to one, or a slice. Only
+	// unary methods can have none.
+	switch {
+	case outType == nil:
+	case isStructPointer(outType), outType.Kind() == reflect.Struct:
+	case outType.Kind() == reflect.Slice && kind == unary:
+	default:
+		return method, fmt.Errorf("OUTPUT is %v, want a struct, a pointer to one, or a slice", outType)
 	}
 
+	fn := reflectedMethod.Func
+	args := func(ctx context.Context, v any) []reflect.Value {
+		args := []reflect.Value{svc, reflect.ValueOf(ctx)}
+		switch inKind {
+		case inPointer:
+			args = append(args, reflect.ValueOf(v))
+		case inValue:
+			args = append(args, reflect.ValueOf(v).Elem())
+		}
+		return args
+	}
 	return Method{
-		In:      reflectedMethod.Type.In(2).Name(),
-		inType:  reflectedMethod.Type.In(2),
-		Out:     reflectedMethod.Type.Out(0).Name(),
-		outType: reflectedMethod.Type.Out(0),
-
-		svc:  svc,
-		name: reflectedMethod.Name,
-		fn:   reflectedMethod.Func,
-	}, true
-}
-
-func (m *Method) docsJSON() any {
-	return map[string]any{
-		"request": Property{
-			Type:       "object",
-			Properties: buildStructDocs(m.inType.Elem()),
+		In:      inType.Elem().Name(),
+		inType:  inType,
+		inKind:  inKind,
+		Out:     typeName(outType),
+		outType: outType,
+
+		svc:    svc,
+		name:   reflectedMethod.Name,
+		doc:    typeDocKey(parentReflectedType, reflectedMethod.Name),
+		fn:     fn,
+		stream: kind,
+
+		newIn: func() any {
+			return reflect.New(inType.Elem()).Interface()
 		},
-		"response": Property{
-			Type:       "object",
-			Properties: buildStructDocs(m.outType.Elem()),
+		invoke: func(ctx context.Context, in any) (any, status.Status) {
+			resp := fn.Call(args(ctx, in))
+			st := resultStatus(resp[len(resp)-1])
+			if len(resp) == 1 {
+				if st.Code == codes.OK {
+					st.Code = codes.NoContent
+				}
+				return nil, st
+			}
+			return resp[0].Interface(), st
 		},
-	}
+		send: func(ctx context.Context, in any, send func(out any) error) status.Status {
+			sendFn := reflect.MakeFunc(sendType, func(args []reflect.Value) []reflect.Value {
+				err := send(args[0].Interface())
+				if err == nil {
+					return []reflect.Value{reflect.Zero(errorType)}
+				}
+				return []reflect.Value{reflect.ValueOf(&err).Elem()}
+			})
+			resp := fn.Call(append(args(ctx, in), sendFn))
+			return resultStatus(resp[0])
+		},
+		recv: func(ctx context.Context, in any) (reflect.Value, status.Status) {
+			resp := fn.Call(args(ctx, in))
+			return resp[0], resultStatus(resp[1])
+		},
+	}, nil
 }
 
-// buildStructDocs support maps/slices fields
-func buildStructDocs(v reflect.Type) map[string]Property {
-	out := make(map[string]Property)
-	NumField := v.NumField()
-	for f := 0; f < NumField; f++ {
-		field := v.Field(f)
+// inArg is how a method takes its INPUT.
+type inArg int
 
-		if !field.IsExported() {
-			continue
-		}
+const (
+	// fn(ctx context.Context, in *INPUT)
+	inPointer inArg = iota
+	// fn(ctx context.Context, in INPUT)
+	inValue
+	// fn(ctx context.Context)
+	inNone
+)
 
-		var fieldName string
-		jsonTag, ok := field.Tag.Lookup("json")
-		if ok {
-			jsonTagParts := strings.Split(jsonTag, ",")
-			fieldName = jsonTagParts[0]
-		}
+// emptyInputType is the INPUT of methods without one.
+var emptyInputType = reflect.TypeOf(&struct{}{})
 
-		var example string
-		exampleTag, ok := field.Tag.Lookup("example")
-		if ok {
-			example = exampleTag
-		}
+var statusType = reflect.TypeOf(status.Status{})
 
-		var format string
-		formatTag, ok := field.Tag.Lookup("format")
-		if ok {
-			format = formatTag
-		}
+// resultStatus is the status.Status or error v, a result of a
+// method. see status.FromError.
+func resultStatus(v reflect.Value) status.Status {
+	if st, ok := v.Interface().(status.Status); ok {
+		return st
+	}
+	err, _ := v.Interface().(error)
+	return status.FromError(err)
+}
 
-		//var validate string
-		validateTag, ok := field.Tag.Lookup("validate")
-		if ok {
-			format = validateTag
-		}
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
 
-		var fieldTypeName string
-		switch field.Type {
-		case reflect.TypeOf(uuid.UUID{}):
-			out[fieldName] = Property{
-				Type:    "string",
-				Format:  "uuid",
-				Example: uuid.NewString(),
-			}
-		case reflect.TypeOf(time.Time{}):
-			out[fieldName] = Property{
-				Type:    "string",
-				Format:  "rfc3339",
-				Example: time.Now().UTC().Format(time.RFC3339),
-			}
-		default:
-			switch field.Type.Kind() {
-			case reflect.Struct:
-				out[fieldName] = Property{
-					Type:       "object",
-					Properties: buildStructDocs(field.Type),
-					Example:    example,
-					//Validate:   validate,
-					Format: format,
-				}
-			case reflect.Map:
-				out[fieldName] = Property{
-					Type:    "object",
-					Example: example,
-					//Validate: validate,
-					Format: format,
-				}
-			case reflect.Slice:
-				out[fieldName] = Property{
-					Type:    "array",
-					Example: example,
-					//Validate: validate,
-					Format: format,
-				}
-			default:
-				fieldTypeName = field.Type.Kind().String()
-				out[fieldName] = Property{
-					Type:    fieldTypeName,
-					Example: example,
-					//Validate: validate,
-					Format: format,
-				}
-			}
-		}
+// results formats the results of fnType, e.g. (*User, error)
+func results(fnType reflect.Type) string {
+	out := make([]string, fnType.NumOut())
+	for i := range out {
+		out[i] = fnType.Out(i).String()
+	}
+	return "(" + strings.Join(out, ", ") + ")"
+}
+
+func isStructPointer(t reflect.Type) bool {
+	return t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct
+}
+
+// MethodDocs is served at GET /<type>/<method>/help
+//
+// The INPUT/OUTPUT of the method are JSON Schemas sharing
+// a single set of $defs.
+type MethodDocs struct {
+	Schema      string  `json:"$schema"`
+	Description string  `json:"description,omitempty"`
+	Request     *Schema `json:"request"`
+	Response    *Schema `json:"response"`
+	// Stream is set when the method streams many Responses.
+	Stream bool               `json:"stream,omitempty"`
+	Defs   map[string]*Schema `json:"$defs,omitempty"`
+}
 
+func (m *Method) docsJSON() MethodDocs {
+	g := newSchemaGenerator("#/$defs/")
+	return MethodDocs{
+		Schema:      JSONSchemaDialect,
+		Description: m.doc.doc(),
+		Request:     g.schemaOf(m.inType.Elem()),
+		Response:    m.responseSchema(g),
+		Stream:      m.stream != unary,
+		Defs:        g.defs,
 	}
-	return out
 }
 
-type Property struct {
-	Type       string              `json:"type,omitempty"`
-	Format     string              `json:"format,omitempty"`
-	Validate   string              `json:"validate,omitempty"`
-	Example    string              `json:"example,omitempty"`
-	Properties map[string]Property `json:"properties,omitempty"`
+// responseSchema is the schema of the OUTPUT of m, or nil when
+// it has none.
+func (m *Method) responseSchema(g *schemaGenerator) *Schema {
+	switch {
+	case m.outType == nil:
+		return nil
+	case m.outType.Kind() == reflect.Pointer:
+		return g.schemaOf(m.outType.Elem())
+	default:
+		return g.schemaOf(m.outType)
+	}
 }
 
 // NewIn mints a new inType.
@@ -341,12 +660,9 @@ func (m *Method) NewIn() reflect.Value {
 	return reflect.New(m.inType)
 }
 
+// Invoke calls a unary method, without validating in.
 func (m *Method) Invoke(ctx context.Context, in any) (any, status.Status) {
-	resp := m.fn.Call([]reflect.Value{m.svc, reflect.ValueOf(ctx), reflect.ValueOf(in)})
-
-	out := resp[0].Interface()
-	status := resp[1].Interface().(status.Status)
-	return out, status
+	return m.invoke(ctx, in)
 }
 
 func ListExportedMethodNames(v reflect.Type) []string {
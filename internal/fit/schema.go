@@ -0,0 +1,301 @@
+package fit
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JSONSchemaDialect is the JSON Schema draft schemas are written in.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema (draft 2020-12) describing method
+// inputs and outputs. It is also a valid OpenAPI 3.1 schema object.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Examples             []any              `json:"examples,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	MaxProperties        *int               `json:"maxProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// SchemaType is the "type" keyword. It encodes as a string when
+// it holds a single type, and as an array otherwise.
+//
+//	"string"
+//	["string", "null"]
+type SchemaType []string
+
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = SchemaType{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// Is reports whether typ is one of the types.
+func (t SchemaType) Is(typ string) bool {
+	for _, v := range t {
+		if v == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaGenerator builds schemas from go types. Named types are
+// emitted once into defs and referenced with refPrefix, which
+// lets recursive types terminate.
+type schemaGenerator struct {
+	refPrefix string
+	defs      map[string]*Schema
+}
+
+func newSchemaGenerator(refPrefix string) *schemaGenerator {
+	return &schemaGenerator{
+		refPrefix: refPrefix,
+		defs:      make(map[string]*Schema),
+	}
+}
+
+var (
+	uuidType          = reflect.TypeOf(uuid.UUID{})
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case uuidType:
+		return &Schema{Type: SchemaType{"string"}, Format: "uuid"}
+	case timeType:
+		return &Schema{Type: SchemaType{"string"}, Format: "date-time"}
+	}
+	if t.Kind() != reflect.Pointer && (t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)) {
+		return &Schema{Type: SchemaType{"string"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schemaOf(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}}
+	case reflect.String:
+		return &Schema{Type: SchemaType{"string"}}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as base64.
+			return &Schema{Type: SchemaType{"string"}, ContentEncoding: "base64"}
+		}
+		return &Schema{Type: SchemaType{"array"}, Items: g.schemaOf(t.Elem())}
+	case reflect.Array:
+		return &Schema{Type: SchemaType{"array"}, Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: SchemaType{"object"}, AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = &Schema{}
+			*g.defs[name] = *g.structSchema(t)
+			g.defs[name].Description = typeDocKey(t, "").doc()
+		}
+		return &Schema{Ref: g.refPrefix + name}
+	default:
+		// interfaces, and anything else encoding/json
+		// can't be described ahead of time.
+		return &Schema{}
+	}
+}
+
+// nullable allows s to also be null.
+func nullable(s *Schema) *Schema {
+	switch {
+	case s.Ref != "":
+		return &Schema{AnyOf: []*Schema{s, {Type: SchemaType{"null"}}}}
+	case len(s.Type) == 0 || s.Type.Is("null"):
+		return s
+	default:
+		s.Type = append(s.Type, "null")
+		return s
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       SchemaType{"object"},
+		Properties: make(map[string]*Schema),
+	}
+	for _, field := range jsonFields(t) {
+		prop := g.schemaOf(field.Type)
+		if field.quoted && prop.Ref == "" {
+			prop.Type = SchemaType{"string"}
+		}
+		required := !field.omitEmpty && field.Type.Kind() != reflect.Pointer
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			validateRequired, validateOmitEmpty := applyValidateTag(prop, tag)
+			required = validateRequired || (required && !validateOmitEmpty)
+		}
+		if prop.Ref == "" && prop.AnyOf == nil {
+			// an explicit format wins over one implied by validate.
+			if format, ok := field.Tag.Lookup("format"); ok {
+				prop.Format = format
+			}
+			if example, ok := field.Tag.Lookup("example"); ok {
+				prop.Examples = []any{parseExample(prop.Type, example)}
+			}
+		}
+		prop.Description = field.doc
+		s.Properties[field.name] = prop
+		if required {
+			s.Required = append(s.Required, field.name)
+		}
+	}
+	return s
+}
+
+// jsonField is a struct field as encoding/json sees it.
+type jsonField struct {
+	reflect.StructField
+	name      string
+	omitEmpty bool
+	quoted    bool
+	// doc is the doc comment of the field. see RegisterDocs.
+	doc string
+}
+
+// jsonFields lists the fields encoding/json would encode for t,
+// including those promoted from embedded structs.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	seen := make(map[string]bool)
+	var embedded []reflect.StructField
+	for f := 0; f < t.NumField(); f++ {
+		field := t.Field(f)
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(jsonTag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// promoted fields lose to the fields of
+				// the outer struct, so visit them last.
+				embedded = append(embedded, field)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		seen[name] = true
+		fields = append(fields, jsonField{
+			StructField: field,
+			name:        name,
+			omitEmpty:   hasTagOption(opts, "omitempty"),
+			quoted:      hasTagOption(opts, "string"),
+			doc:         typeDocKey(t, field.Name).doc(),
+		})
+	}
+
+	for _, field := range embedded {
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		for _, promoted := range jsonFields(ft) {
+			if seen[promoted.name] {
+				continue
+			}
+			seen[promoted.name] = true
+			// a nil embedded pointer omits its fields.
+			if field.Type.Kind() == reflect.Pointer {
+				promoted.omitEmpty = true
+			}
+			fields = append(fields, promoted)
+		}
+	}
+	return fields
+}
+
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExample converts an example tag into a value matching
+// the schema type, falling back to the raw string.
+func parseExample(typ SchemaType, example string) any {
+	if typ.Is("string") {
+		return example
+	}
+	var v any
+	if err := json.Unmarshal([]byte(example), &v); err != nil {
+		return example
+	}
+	return v
+}
+
+// schemaName is the name a named type is defined under,
+// e.g. users.User.
+func schemaName(t reflect.Type) string {
+	name := path.Base(t.PkgPath()) + "." + t.Name()
+	return strings.NewReplacer("[", "_", "]", "", "/", "_", "*", "", ",", "_", " ", "").Replace(name)
+}
@@ -0,0 +1,150 @@
+package fit
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type schemaNode struct {
+	Value    int          `json:"value"`
+	Next     *schemaNode  `json:"next"`
+	Children []schemaNode `json:"children,omitempty"`
+}
+
+type schemaBase struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// EmbeddedNote is exported, as encoding/json skips embedded
+// pointers to unexported structs.
+type EmbeddedNote struct {
+	Note string `json:"note"`
+}
+
+type schemaEmbedded struct {
+	schemaBase
+	*EmbeddedNote
+	Name string `json:"name,omitempty"`
+}
+
+type schemaTags struct {
+	A string `json:"a"`
+	B string `json:"b,omitempty"`
+	C string `json:"-"`
+	D int    `json:"d,string"`
+	E string
+	f string
+}
+
+type schemaPointers struct {
+	Int   *int        `json:"int"`
+	Time  *time.Time  `json:"time"`
+	Tags  *schemaTags `json:"tags"`
+	Any   *any        `json:"any"`
+	Slice []*string   `json:"slice"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{
+			name: "recursive",
+			v:    schemaNode{},
+			want: `{"$ref":"#/$defs/fit.schemaNode","$defs":{"fit.schemaNode":{"type":"object","properties":{
+				"value":{"type":"integer"},
+				"next":{"anyOf":[{"$ref":"#/$defs/fit.schemaNode"},{"type":"null"}]},
+				"children":{"type":"array","items":{"$ref":"#/$defs/fit.schemaNode"}}
+			},"required":["value"]}}}`,
+		},
+		{
+			name: "embedded",
+			v:    schemaEmbedded{},
+			want: `{"$ref":"#/$defs/fit.schemaEmbedded","$defs":{"fit.schemaEmbedded":{"type":"object","properties":{
+				"name":{"type":"string"},
+				"id":{"type":"string"},
+				"note":{"type":"string"}
+			},"required":["id"]}}}`,
+		},
+		{
+			name: "tags",
+			v:    schemaTags{},
+			want: `{"$ref":"#/$defs/fit.schemaTags","$defs":{"fit.schemaTags":{"type":"object","properties":{
+				"a":{"type":"string"},
+				"b":{"type":"string"},
+				"d":{"type":"string"},
+				"E":{"type":"string"}
+			},"required":["a","d","E"]}}}`,
+		},
+		{
+			name: "pointers",
+			v:    schemaPointers{},
+			want: `{"$ref":"#/$defs/fit.schemaPointers","$defs":{
+				"fit.schemaPointers":{"type":"object","properties":{
+					"int":{"type":["integer","null"]},
+					"time":{"type":["string","null"],"format":"date-time"},
+					"tags":{"anyOf":[{"$ref":"#/$defs/fit.schemaTags"},{"type":"null"}]},
+					"any":{},
+					"slice":{"type":"array","items":{"type":["string","null"]}}
+				},"required":["slice"]},
+				"fit.schemaTags":{"type":"object","properties":{
+					"a":{"type":"string"},
+					"b":{"type":"string"},
+					"d":{"type":"string"},
+					"E":{"type":"string"}
+				},"required":["a","d","E"]}
+			}}`,
+		},
+		{
+			name: "pointer to a scalar",
+			v:    new(string),
+			want: `{"type":["string","null"]}`,
+		},
+		{
+			name: "bytes",
+			v:    []byte{},
+			want: `{"type":"string","contentEncoding":"base64"}`,
+		},
+		{
+			name: "map",
+			v:    map[string]*int{},
+			want: `{"type":"object","additionalProperties":{"type":["integer","null"]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newSchemaGenerator("#/$defs/")
+			s := g.schemaOf(reflect.TypeOf(tt.v))
+			if len(g.defs) > 0 {
+				s.Defs = g.defs
+			}
+			assertJSON(t, s, tt.want)
+		})
+	}
+}
+
+// assertJSON fails t unless got encodes to the same JSON as want,
+// whatever the order of their keys.
+func assertJSON(t *testing.T, got any, want string) {
+	t.Helper()
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	var g, w any
+	if err := json.Unmarshal(data, &g); err != nil {
+		t.Fatalf("failed to decode %s: %v", data, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("failed to decode want: %v", err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		wantData, _ := json.Marshal(w)
+		t.Errorf("got  %s\nwant %s", data, wantData)
+	}
+}
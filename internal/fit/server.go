@@ -0,0 +1,214 @@
+package fit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Server hosts many RPCs, each at /<type>, alongside endpoints
+// describing all of them.
+//
+//	GET /help // gets the services and their methods, see ServerDocs
+//	GET /_descriptor // gets everything tools need, see Descriptor
+//	POST /jsonrpc // calls any method, see JSONRPC
+type Server struct {
+	rpcs    []*RPC
+	jsonRPC *JSONRPC
+}
+
+// serverPaths are served by a Server, so no RPC can be named after them.
+var serverPaths = []string{"help", "_descriptor", "jsonrpc"}
+
+// NewServer hosts rpcs. It fails when two of them are named the
+// same, ignoring case as routes do, or after a path of the Server.
+func NewServer(rpcs ...*RPC) (*Server, error) {
+	for i, rpc := range rpcs {
+		for _, p := range serverPaths {
+			if strings.EqualFold(rpc.Name(), p) {
+				return nil, fmt.Errorf("fit: NewServer: %v is a path of the server", rpc.Name())
+			}
+		}
+		for _, other := range rpcs[:i] {
+			if strings.EqualFold(rpc.Name(), other.Name()) {
+				return nil, fmt.Errorf("fit: NewServer: %v and %v are both served at /%v", other.Name(), rpc.Name(), rpc.Name())
+			}
+		}
+	}
+	return &Server{
+		rpcs:    rpcs,
+		jsonRPC: NewJSONRPC(rpcs...),
+	}, nil
+}
+
+// ServerDocs is served at GET /help
+type ServerDocs struct {
+	Services []ServiceIndex `json:"services"`
+}
+
+// ServiceIndex is a service of a Server, and where its docs are.
+type ServiceIndex struct {
+	Name    string `json:"name"`
+	Help    string `json:"help"`
+	UI      string `json:"ui"`
+	OpenAPI string `json:"openapi"`
+	ServiceDocs
+}
+
+func (s *Server) docsJSON() ServerDocs {
+	services := make([]ServiceIndex, 0, len(s.rpcs))
+	for _, rpc := range s.rpcs {
+		services = append(services, ServiceIndex{
+			Name:        rpc.Name(),
+			Help:        "/" + rpc.Name() + "/help",
+			UI:          "/" + rpc.Name() + "/help/ui",
+			OpenAPI:     "/" + rpc.Name() + "/openapi.json",
+			ServiceDocs: rpc.docsJSON(),
+		})
+	}
+	return ServerDocs{Services: services}
+}
+
+// Descriptor is served at GET /_descriptor, so tools can find
+// every service and method of a running Server, like gRPC
+// reflection.
+//
+// The INPUT/OUTPUT of the methods are JSON Schemas sharing a
+// single set of $defs.
+type Descriptor struct {
+	Schema   string              `json:"$schema"`
+	Services []ServiceDescriptor `json:"services"`
+	// JSONRPC is the path of the JSON-RPC endpoint, where methods
+	// are named <type>.<method>.
+	JSONRPC string             `json:"jsonrpc"`
+	Defs    map[string]*Schema `json:"$defs,omitempty"`
+}
+
+type ServiceDescriptor struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Path        string             `json:"path"`
+	Methods     []MethodDescriptor `json:"methods"`
+}
+
+type MethodDescriptor struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Path is where the method is called on POST.
+	Path string `json:"path"`
+	// GetPath is where it is called on GET too, if it is, with
+	// {name} segments bound to INPUT. see Get.
+	GetPath string `json:"get_path,omitempty"`
+	Stream  bool   `json:"stream,omitempty"`
+	// Idempotent is set when it honors the Idempotency-Key
+	// header. see Idempotent.
+	Idempotent bool    `json:"idempotent,omitempty"`
+	Request    *Schema `json:"request"`
+	Response   *Schema `json:"response"`
+}
+
+func (s *Server) descriptor() Descriptor {
+	g := newSchemaGenerator("#/$defs/")
+	services := make([]ServiceDescriptor, 0, len(s.rpcs))
+	for _, rpc := range s.rpcs {
+		svc := ServiceDescriptor{
+			Name:        rpc.Name(),
+			Description: rpc.doc.doc(),
+			Path:        "/" + rpc.Name(),
+		}
+		for _, name := range rpc.docsJSON().Methods {
+			m := rpc.methods[name]
+			md := MethodDescriptor{
+				Name:        name,
+				Description: m.doc.doc(),
+				Path:        svc.Path + "/" + name,
+				Stream:      m.stream != unary,
+				Idempotent:  m.idempotency != nil && m.stream == unary,
+				Request:     g.schemaOf(m.inType.Elem()),
+				Response:    m.responseSchema(g),
+			}
+			if m.get {
+				md.GetPath = md.Path
+				if m.getPath != "" {
+					md.GetPath += "/" + m.getPath
+				}
+			}
+			svc.Methods = append(svc.Methods, md)
+		}
+		services = append(services, svc)
+	}
+	return Descriptor{
+		Schema:   JSONSchemaDialect,
+		Services: services,
+		JSONRPC:  "/jsonrpc",
+		Defs:     g.defs,
+	}
+}
+
+// routes are those of the Server itself, after / rather than /<type>.
+func (s *Server) routes() []route {
+	return []route{
+		docRoute("/help", "application/json", func() ([]byte, error) { return json.Marshal(s.docsJSON()) }),
+		docRoute("/_descriptor", "application/json", func() ([]byte, error) { return json.Marshal(s.descriptor()) }),
+		{
+			path:  "/jsonrpc",
+			verbs: []string{http.MethodPost},
+			fiber: s.jsonRPC.FiberHandler(),
+			http:  func(w http.ResponseWriter, r *http.Request, _ map[string]string) { s.jsonRPC.ServeHTTP(w, r) },
+		},
+	}
+}
+
+// MountFiberApp serves the Server, and each RPC at /<type>, on app.
+func (s *Server) MountFiberApp(app *fiber.App) {
+	routes := s.routes()
+	for _, rt := range routes {
+		for _, verb := range rt.verbs {
+			app.Add(verb, rt.fiberPath(), rt.fiber)
+			if verb == fiber.MethodGet {
+				app.Add(fiber.MethodHead, rt.fiberPath(), rt.fiber)
+			}
+		}
+		// other verbs are answered the same as NewNetHttpHandler.
+		app.All(rt.fiberPath(), func(c *fiber.Ctx) error {
+			st, allow := routeError(routes, splitPath(c.Path()), c.Path())
+			c.Set(fiber.HeaderAllow, allow)
+			return sendFiberError(c, st)
+		})
+	}
+	for _, rpc := range s.rpcs {
+		rpc.MountFiberApp(app)
+	}
+}
+
+// NewNetHttpHandler serves the Server, and each RPC at /<type>,
+// the same as MountFiberApp.
+func (s *Server) NewNetHttpHandler() http.HandlerFunc {
+	routes := s.routes()
+	handlers := make(map[string]http.HandlerFunc, len(s.rpcs))
+	for _, rpc := range s.rpcs {
+		handlers[strings.ToLower(rpc.Name())] = rpc.NewNetHttpHandler()
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.EscapedPath()
+		parts := splitPath(p)
+		if handler, ok := handlers[strings.ToLower(parts[0])]; ok {
+			handler(w, r)
+			return
+		}
+		for _, rt := range routes {
+			if params, ok := rt.match(parts); ok && rt.allows(r.Method) {
+				rt.http(w, r, params)
+				return
+			}
+		}
+		st, allow := routeError(routes, parts, p)
+		if allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		writeHTTPError(w, r, st)
+	}
+}
@@ -0,0 +1,217 @@
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Detail is a typed detail of a Status. It is encoded with an
+// "@type" member naming it, so clients can decode it back.
+//
+//	{"@type": "ErrorInfo", "reason": "EMAIL_TAKEN", "domain": "users"}
+type Detail interface {
+	DetailType() string
+}
+
+// ErrorInfo is the reason of an error, in a form machines can
+// switch on.
+type ErrorInfo struct {
+	// Reason is a constant, e.g. EMAIL_TAKEN
+	Reason string `json:"reason"`
+	// Domain groups reasons, e.g. users
+	Domain   string            `json:"domain,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// RetryInfo tells the client how long to wait before retrying.
+// Transports send it as a Retry-After header too.
+type RetryInfo struct {
+	RetryDelay time.Duration `json:"-"`
+}
+
+// BadRequest lists the invalid fields of a request.
+type BadRequest struct {
+	FieldViolations []FieldViolation `json:"field_violations"`
+}
+
+// FieldViolation describes why a field of a request is invalid.
+type FieldViolation struct {
+	// Field is the path to the field, e.g. user.email
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// QuotaFailure lists the quotas a request exceeded.
+type QuotaFailure struct {
+	Violations []QuotaViolation `json:"violations"`
+}
+
+type QuotaViolation struct {
+	// Subject is what ran out of quota, e.g. user:3c5d0a6e
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+// PreconditionFailure lists the preconditions a request failed.
+type PreconditionFailure struct {
+	Violations []PreconditionViolation `json:"violations"`
+}
+
+type PreconditionViolation struct {
+	// Type of the precondition, e.g. TOS
+	Type        string `json:"type"`
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+func (ErrorInfo) DetailType() string           { return "ErrorInfo" }
+func (RetryInfo) DetailType() string           { return "RetryInfo" }
+func (BadRequest) DetailType() string          { return "BadRequest" }
+func (QuotaFailure) DetailType() string        { return "QuotaFailure" }
+func (PreconditionFailure) DetailType() string { return "PreconditionFailure" }
+
+func (d ErrorInfo) MarshalJSON() ([]byte, error) {
+	type plain ErrorInfo
+	return marshalDetail(d, plain(d))
+}
+
+func (d RetryInfo) MarshalJSON() ([]byte, error) {
+	return marshalDetail(d, struct {
+		RetryDelay string `json:"retry_delay"`
+	}{d.RetryDelay.String()})
+}
+
+func (d *RetryInfo) UnmarshalJSON(data []byte) error {
+	var v struct {
+		RetryDelay string `json:"retry_delay"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	delay, err := time.ParseDuration(v.RetryDelay)
+	if err != nil {
+		return fmt.Errorf("invalid retry_delay: %v", err)
+	}
+	d.RetryDelay = delay
+	return nil
+}
+
+func (d BadRequest) MarshalJSON() ([]byte, error) {
+	type plain BadRequest
+	return marshalDetail(d, plain(d))
+}
+
+func (d QuotaFailure) MarshalJSON() ([]byte, error) {
+	type plain QuotaFailure
+	return marshalDetail(d, plain(d))
+}
+
+func (d PreconditionFailure) MarshalJSON() ([]byte, error) {
+	type plain PreconditionFailure
+	return marshalDetail(d, plain(d))
+}
+
+// marshalDetail encodes v, an object, with the "@type" of d.
+func marshalDetail(d Detail, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := json.Marshal(d.DetailType())
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	b.WriteString(`{"@type":`)
+	b.Write(typ)
+	if rest := bytes.TrimPrefix(data, []byte("{")); !bytes.HasPrefix(rest, []byte("}")) {
+		b.WriteByte(',')
+		b.Write(rest)
+	} else {
+		b.WriteByte('}')
+	}
+	return b.Bytes(), nil
+}
+
+var (
+	detailsMu sync.RWMutex
+	details   = map[string]reflect.Type{
+		"ErrorInfo":           reflect.TypeOf(ErrorInfo{}),
+		"RetryInfo":           reflect.TypeOf(RetryInfo{}),
+		"BadRequest":          reflect.TypeOf(BadRequest{}),
+		"QuotaFailure":        reflect.TypeOf(QuotaFailure{}),
+		"PreconditionFailure": reflect.TypeOf(PreconditionFailure{}),
+	}
+)
+
+// RegisterDetail lets DecodeDetail decode the type of d. Its
+// MarshalJSON should encode its "@type", see ErrorInfo.
+func RegisterDetail(d Detail) {
+	detailsMu.Lock()
+	defer detailsMu.Unlock()
+	details[d.DetailType()] = reflect.TypeOf(d)
+}
+
+// DecodeDetail decodes a detail into the registered type named
+// by its "@type", or into generic JSON when there is none.
+func DecodeDetail(data []byte) (any, error) {
+	var head struct {
+		Type string `json:"@type"`
+	}
+	json.Unmarshal(data, &head)
+
+	detailsMu.RLock()
+	t, ok := details[head.Type]
+	detailsMu.RUnlock()
+	if !ok {
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %v", head.Type, err)
+	}
+	return v.Elem().Interface(), nil
+}
+
+// WithDetails is a copy of s with details appended.
+//
+//	return nil, status.New(codes.Conflict, "email is taken").WithDetails(status.ErrorInfo{
+//		Reason: "EMAIL_TAKEN",
+//		Domain: "users",
+//	})
+func (s Status) WithDetails(details ...any) Status {
+	all := append(append([]any(nil), s.Details()...), details...)
+	s.details = &all
+	return s
+}
+
+// Details are the details of s, see WithDetails.
+func (s Status) Details() []any {
+	if s.details == nil {
+		return nil
+	}
+	return *s.details
+}
+
+// DetailOf finds the first detail of s of type T.
+//
+//	if info, ok := status.DetailOf[status.RetryInfo](s); ok {
+//		time.Sleep(info.RetryDelay)
+//	}
+func DetailOf[T any](s Status) (T, bool) {
+	for _, d := range s.Details() {
+		if v, ok := d.(T); ok {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
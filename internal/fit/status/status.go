@@ -1,6 +1,7 @@
 package status
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/hyqe/ribose/internal/fit/codes"
@@ -15,15 +16,31 @@ var (
 	NotFound  = Status{Code: codes.NotFound}
 )
 
+// Status is comparable, so it can be checked with ==, which
+// compares its details by identity, and with errors.Is, which
+// ignores them.
 type Status struct {
 	codes.Code
 	Message string
+	// details are sent to the client alongside the code and
+	// message, behind a pointer to keep Status comparable.
+	// see Details and WithDetails.
+	details *[]any
 }
 
 func (s Status) Error() string {
 	return fmt.Sprintf("%v: %v", s.Code, s.Message)
 }
 
+// Is reports whether target is a Status with the same code and
+// message, whatever their details.
+//
+//	errors.Is(err, status.NotFound)
+func (s Status) Is(target error) bool {
+	t, ok := target.(Status)
+	return ok && t.Code == s.Code && t.Message == s.Message
+}
+
 func New(code codes.Code, v any) Status {
 	return Status{
 		Code:    code,
@@ -38,6 +55,23 @@ func Newf(code codes.Code, format string, v ...any) Status {
 	}
 }
 
+// FromError is the Status in the chain of err, found with
+// errors.As, or codes.Internal when there is none. A nil err
+// is OK.
+//
+//	return status.Newf(codes.NotFound, "no user %v", id)
+//	return fmt.Errorf("failed to load user: %w", status.NotFound)
+func FromError(err error) Status {
+	if err == nil {
+		return OK
+	}
+	var s Status
+	if errors.As(err, &s) {
+		return s
+	}
+	return New(codes.Internal, err)
+}
+
 // Pg converts an pq.Error into a Status.
 func Pg(e *pq.Error) Status {
 	return Status{
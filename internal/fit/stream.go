@@ -0,0 +1,278 @@
+package fit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// streamKind is how a method sends its OUTPUT.
+type streamKind int
+
+const (
+	unary streamKind = iota
+	// fn(ctx context.Context, in *INPUT, send func(*OUTPUT) error) status.Status
+	sendStream
+	// fn(ctx context.Context, in *INPUT) (<-chan *OUTPUT, status.Status)
+	chanStream
+)
+
+// streamEvent is the next thing that happened on a stream.
+type streamEvent struct {
+	// open is sent once, when the method starts streaming.
+	open bool
+	out  any
+	// done is the last event, with the status of the method.
+	done bool
+	st   status.Status
+}
+
+// openStream calls a streaming method through the interceptors.
+// The first event is either open, or done when the call failed
+// before it started streaming. The channel is closed after done.
+//
+// Sends block until the event is received, or ctx is done.
+func (s *RPC) openStream(ctx context.Context, method *Method, in any) <-chan streamEvent {
+	events := make(chan streamEvent)
+	emit := func(ev streamEvent) error {
+		select {
+		case events <- ev:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	go func() {
+		defer close(events)
+		_, st := s.intercept(ctx, method, in, func(ctx context.Context, in any) (any, status.Status) {
+			return nil, method.invokeStream(ctx, in,
+				func() error { return emit(streamEvent{open: true}) },
+				func(out any) error { return emit(streamEvent{out: out}) },
+			)
+		})
+		emit(streamEvent{done: true, st: st})
+	}()
+	return events
+}
+
+// invokeStream calls a streaming method, calling open once it
+// starts streaming, and send for each OUTPUT.
+func (m *Method) invokeStream(ctx context.Context, in any, open func() error, send func(out any) error) status.Status {
+	switch m.stream {
+	case sendStream:
+		if err := open(); err != nil {
+			return contextStatus(err)
+		}
+		return m.send(ctx, in, send)
+
+	case chanStream:
+		ch, st := m.recv(ctx, in)
+		if st.Code >= 300 {
+			return st
+		}
+		if err := open(); err != nil {
+			return contextStatus(err)
+		}
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: ch},
+		}
+		for {
+			chosen, out, ok := reflect.Select(cases)
+			if chosen == 0 {
+				return contextStatus(ctx.Err())
+			}
+			if !ok {
+				return st
+			}
+			if err := send(out.Interface()); err != nil {
+				return contextStatus(err)
+			}
+		}
+
+	default:
+		return status.Newf(codes.Internal, "%v does not stream", m.name)
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// streamWriter frames the OUTPUT of a stream as Server-Sent
+// Events, or as newline delimited JSON.
+//
+//	data: {"uuid": "..."}
+//
+//	event: error
+//	data: {"code": 500, "message": "..."}
+//
+// or
+//
+//	{"result": {"uuid": "..."}}
+//	{"error": {"code": 500, "message": "..."}}
+//
+// Server-Sent Events end with an "end" event, so EventSources
+// know not to reconnect.
+type streamWriter struct {
+	sse       bool
+	codec     Codec
+	requestID string
+}
+
+// newStreamWriter picks Server-Sent Events when the Accept header
+// asks for them, and NDJSON otherwise.
+func (s *RPC) newStreamWriter(accept, requestID string) streamWriter {
+	sw := streamWriter{codec: s.codecs["application/json"], requestID: requestID}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == "text/event-stream" {
+			sw.sse = true
+		}
+	}
+	return sw
+}
+
+func (sw streamWriter) contentType() string {
+	if sw.sse {
+		return "text/event-stream"
+	}
+	return "application/x-ndjson"
+}
+
+func (sw streamWriter) write(w io.Writer, ev streamEvent) error {
+	var (
+		data  []byte
+		event string
+		err   error
+	)
+	switch {
+	case ev.open:
+		return nil
+	case ev.done && ev.st.Code >= 300:
+		event = "error"
+		data, err = sw.codec.Marshal(newErrorBody(ev.st, sw.requestID))
+	case ev.done:
+		event = "end"
+		data, err = sw.codec.Marshal(struct {
+			Code codes.Code `json:"code"`
+		}{ev.st.Code})
+	default:
+		event = "result"
+		data, err = sw.codec.Marshal(ev.out)
+	}
+	if err != nil {
+		event = "error"
+		data, _ = sw.codec.Marshal(newErrorBody(status.Newf(codes.Internal, "failed to encode response: %v", err), sw.requestID))
+	}
+
+	var b bytes.Buffer
+	if sw.sse {
+		if event != "result" {
+			b.WriteString("event: " + event + "\n")
+		}
+		b.WriteString("data: ")
+		b.Write(data)
+		b.WriteString("\n\n")
+	} else {
+		if event == "end" {
+			return nil
+		}
+		b.WriteString(`{"` + event + `":`)
+		b.Write(data)
+		b.WriteString("}\n")
+	}
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// pump writes events until the stream is done, or writing fails
+// because the client went away, in which case cancel stops the
+// method.
+func (sw streamWriter) pump(w io.Writer, flush func() error, events <-chan streamEvent, cancel context.CancelFunc) {
+	defer func() {
+		cancel()
+		for range events {
+		}
+	}()
+	if err := flush(); err != nil {
+		return
+	}
+	for ev := range events {
+		if err := sw.write(w, ev); err != nil {
+			return
+		}
+		if err := flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *RPC) fiberStream(method *Method) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		in, _, st := s.fiberIn(c, method)
+		if st.Code >= 300 {
+			return sendFiberError(c, st)
+		}
+		ctx, md, cancel := fiberContext(c, TransportHTTP)
+		events := s.openStream(ctx, method, in)
+		first := <-events
+		setFiberHeader(c, md)
+		if first.done {
+			cancel()
+			return sendFiberError(c, first.st)
+		}
+
+		requestID := c.GetRespHeader(fiber.HeaderXRequestID, c.Get(fiber.HeaderXRequestID))
+		sw := s.newStreamWriter(c.Get(fiber.HeaderAccept), requestID)
+		c.Set(fiber.HeaderContentType, sw.contentType())
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			sw.pump(w, w.Flush, events, cancel)
+		})
+		return nil
+	}
+}
+
+func (s *RPC) netHttpStream(method *Method) func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		in, _, st := s.netHttpIn(w, r, method, params)
+		if st.Code >= 300 {
+			writeHTTPError(w, r, st)
+			return
+		}
+		ctx, md := httpContext(w, r, TransportHTTP)
+		ctx, cancel := context.WithCancel(ctx)
+		events := s.openStream(ctx, method, in)
+		first := <-events
+		setHTTPHeader(w, md)
+		if first.done {
+			cancel()
+			writeHTTPError(w, r, first.st)
+			return
+		}
+
+		requestID := w.Header().Get("X-Request-Id")
+		if requestID == "" {
+			requestID = r.Header.Get("X-Request-Id")
+		}
+		sw := s.newStreamWriter(r.Header.Get("Accept"), requestID)
+		w.Header().Set("Content-Type", sw.contentType())
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flush := func() error {
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			return r.Context().Err()
+		}
+		sw.pump(w, flush, events, cancel)
+	}
+}
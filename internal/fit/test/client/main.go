@@ -0,0 +1,202 @@
+// Run this file to check how client.Client retries, backs off and
+// decodes errors, against servers answering as scripted. It exits
+// with 1 when it doesn't behave.
+//
+//	go run ./internal/fit/test/client
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hyqe/ribose/internal/fit/client"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+type EchoRequest struct {
+	Message string `json:"message"`
+}
+
+type EchoResponse struct {
+	Message string `json:"message"`
+}
+
+// reply is what the server answers to an attempt.
+type reply struct {
+	code   int
+	header map[string]string
+	body   string
+}
+
+// testCase is the replies of the server, in order, and what the
+// client must make of them. The last reply repeats.
+type testCase struct {
+	name    string
+	replies []reply
+	retries int
+	backoff time.Duration
+
+	attempts int
+	code     codes.Code
+	message  string
+	// minWait and maxWait bound the time the client waits in
+	// total, when they are set.
+	minWait time.Duration
+	maxWait time.Duration
+	check   func(out *EchoResponse, st status.Status) error
+}
+
+var ok = reply{code: 200, header: map[string]string{"Content-Type": "application/json"}, body: `{"message":"hi"}`}
+
+var cases = []testCase{
+	{
+		name: "honors Retry-After",
+		replies: []reply{
+			{code: 503, header: map[string]string{"Retry-After": "1"}, body: "overloaded"},
+			ok,
+		},
+		retries:  3,
+		backoff:  time.Millisecond,
+		attempts: 2,
+		code:     codes.OK,
+		minWait:  time.Second,
+		check: func(out *EchoResponse, st status.Status) error {
+			if out.Message != "hi" {
+				return fmt.Errorf("got output %+v, want hi", out)
+			}
+			return nil
+		},
+	},
+	{
+		name: "prefers RetryInfo to Retry-After",
+		replies: []reply{
+			{code: 429, header: map[string]string{"Retry-After": "10", "Content-Type": "application/json"},
+				body: `{"code":429,"message":"slow down","details":[{"@type":"RetryInfo","retry_delay":"50ms"}]}`},
+			ok,
+		},
+		retries:  1,
+		backoff:  time.Millisecond,
+		attempts: 2,
+		code:     codes.OK,
+		minWait:  50 * time.Millisecond,
+		maxWait:  5 * time.Second,
+	},
+	{
+		name: "backs off exponentially",
+		replies: []reply{
+			{code: 502, body: "bad gateway"},
+			{code: 504, body: "gateway timeout"},
+			ok,
+		},
+		retries:  2,
+		backoff:  20 * time.Millisecond,
+		attempts: 3,
+		code:     codes.OK,
+		minWait:  60 * time.Millisecond,
+	},
+	{
+		name:     "gives up after its retries",
+		replies:  []reply{{code: 503, body: "down for maintenance\n"}},
+		retries:  2,
+		backoff:  time.Millisecond,
+		attempts: 3,
+		code:     codes.ServiceUnavailable,
+		message:  "down for maintenance",
+	},
+	{
+		name: "decodes an ErrorBody with details",
+		replies: []reply{
+			{code: 409, header: map[string]string{"Content-Type": "application/json"},
+				body: `{"code":409,"message":"email is taken","request_id":"abc","details":[` +
+					`{"@type":"ErrorInfo","reason":"EMAIL_TAKEN","domain":"users"},` +
+					`{"@type":"BadRequest","field_violations":[{"field":"email","description":"is taken"}]},` +
+					`{"@type":"Unknown","n":1}]}`},
+		},
+		retries:  3,
+		attempts: 1,
+		code:     codes.Conflict,
+		message:  "email is taken",
+		check: func(out *EchoResponse, st status.Status) error {
+			info, ok := status.DetailOf[status.ErrorInfo](st)
+			if !ok || info.Reason != "EMAIL_TAKEN" || info.Domain != "users" {
+				return fmt.Errorf("got ErrorInfo %+v, %v", info, ok)
+			}
+			br, ok := status.DetailOf[status.BadRequest](st)
+			if !ok || len(br.FieldViolations) != 1 || br.FieldViolations[0].Field != "email" {
+				return fmt.Errorf("got BadRequest %+v, %v", br, ok)
+			}
+			unknown, ok := status.DetailOf[map[string]any](st)
+			if !ok || unknown["@type"] != "Unknown" {
+				return fmt.Errorf("got unknown detail %v, %v", unknown, ok)
+			}
+			return nil
+		},
+	},
+}
+
+func main() {
+	failed := 0
+	for _, tc := range cases {
+		if err := tc.run(); err != nil {
+			failed++
+			fmt.Printf("FAIL %v: %v\n", tc.name, err)
+			continue
+		}
+		fmt.Printf("ok   %v\n", tc.name)
+	}
+	if failed > 0 {
+		fmt.Printf("%v failed\n", failed)
+		os.Exit(1)
+	}
+}
+
+func (tc testCase) run() error {
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		rep := tc.replies[len(tc.replies)-1]
+		if attempts < len(tc.replies) {
+			rep = tc.replies[attempts]
+		}
+		attempts++
+		mu.Unlock()
+		for k, v := range rep.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(rep.code)
+		fmt.Fprint(w, rep.body)
+	}))
+	defer srv.Close()
+
+	c := client.New(srv.URL, client.WithRetries(tc.retries, tc.backoff))
+	out := new(EchoResponse)
+	start := time.Now()
+	st := c.Call(context.Background(), "test.Service", "Echo", &EchoRequest{Message: "hi"}, out)
+	waited := time.Since(start)
+
+	switch {
+	case attempts != tc.attempts:
+		return fmt.Errorf("made %v attempts, want %v", attempts, tc.attempts)
+	case st.Code != tc.code:
+		return fmt.Errorf("got code %v, want %v: %v", st.Code, tc.code, st.Message)
+	case tc.message != "" && st.Message != tc.message:
+		return fmt.Errorf("got message %q, want %q", st.Message, tc.message)
+	case waited < tc.minWait:
+		return fmt.Errorf("waited %v, want at least %v", waited, tc.minWait)
+	case tc.maxWait > 0 && waited > tc.maxWait:
+		return fmt.Errorf("waited %v, want at most %v", waited, tc.maxWait)
+	}
+	if tc.check != nil {
+		return tc.check(out, st)
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+// Run this file to check that MountFiberApp and NewNetHttpHandler
+// serve an RPC the same way. It exits with 1 when they don't.
+//
+//	go run ./internal/fit/test/conformance
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hyqe/ribose/internal/fit"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+type Service struct{}
+
+type EchoRequest struct {
+	Message string `json:"message" validate:"required"`
+}
+
+type EchoResponse struct {
+	Message string `json:"message"`
+}
+
+func (Service) Echo(ctx context.Context, in *EchoRequest) (*EchoResponse, status.Status) {
+	return &EchoResponse{Message: in.Message}, status.OK
+}
+
+type GetRequest struct {
+	ID string `json:"id" path:"id"`
+}
+
+func (Service) Get(ctx context.Context, in *GetRequest) (*EchoResponse, status.Status) {
+	return &EchoResponse{Message: in.ID}, status.OK
+}
+
+func (Service) List(ctx context.Context) ([]EchoResponse, status.Status) {
+	return []EchoResponse{{Message: "a"}, {Message: "b"}}, status.OK
+}
+
+func (Service) Delete(ctx context.Context, in *GetRequest) status.Status {
+	return status.OK
+}
+
+func (Service) Fail(ctx context.Context) error {
+	return fmt.Errorf("failed to fail: %w", status.New(codes.NotFound, "nothing here"))
+}
+
+type CountRequest struct {
+	N int `json:"n"`
+}
+
+type Count struct {
+	I int `json:"i"`
+}
+
+func (Service) Count(ctx context.Context, in *CountRequest, send func(*Count) error) status.Status {
+	for i := 0; i < in.N; i++ {
+		if err := send(&Count{I: i}); err != nil {
+			return status.New(codes.RequestTimeout, err)
+		}
+	}
+	return status.OK
+}
+
+type NextRequest struct {
+	Step int `json:"step"`
+}
+
+type Next struct {
+	N int `json:"n"`
+}
+
+// next counts the calls of Next, to tell those replayed for an
+// Idempotency-Key apart.
+var next atomic.Int64
+
+func (Service) Next(ctx context.Context, in *NextRequest) (*Next, status.Status) {
+	return &Next{N: int(next.Add(int64(in.Step)))}, status.OK
+}
+
+// testCase is a request, and what both transports must answer.
+type testCase struct {
+	verb   string
+	path   string
+	header map[string]string
+	body   string
+
+	code        int
+	contentType string
+	allow       string
+	// want is the body, compared as JSON unless contentType
+	// isn't JSON. It is ignored when empty.
+	want string
+}
+
+var cases = []testCase{
+	{verb: "GET", path: "/help", code: 200, contentType: "application/json",
+		want: `{"methods":["Count","Delete","Echo","Fail","Get","List","Next"]}`},
+	{verb: "HEAD", path: "/help", code: 200, contentType: "application/json"},
+	{verb: "GET", path: "/Echo/help", code: 200, contentType: "application/json"},
+	{verb: "GET", path: "/openapi.json", code: 200, contentType: "application/json"},
+	{verb: "GET", path: "/openapi.yaml", code: 200, contentType: "application/yaml"},
+	{verb: "GET", path: "/help/ui", code: 200, contentType: "text/html"},
+	{verb: "POST", path: "/help", code: 405, contentType: "application/json", allow: "GET",
+		want: `{"code":405,"message":"method not allowed, use GET"}`},
+
+	{verb: "POST", path: "/Echo", body: `{"message":"hi"}`, code: 200, contentType: "application/json",
+		want: `{"message":"hi"}`},
+	{verb: "POST", path: "/Echo/", body: `{"message":"hi"}`, code: 200, contentType: "application/json",
+		want: `{"message":"hi"}`},
+	{verb: "POST", path: "/Echo", body: `{}`, code: 400, contentType: "application/json",
+		want: `{"code":400,"message":"validation failed","details":[{"@type":"BadRequest","field_violations":[{"field":"message","description":"is required"}]}]}`},
+	{verb: "POST", path: "/Echo", body: `{`, code: 400, contentType: "application/json"},
+	{verb: "POST", path: "/Echo", body: `{"message":"hi"}`, header: map[string]string{"Accept": "text/plain"}, code: 406, contentType: "application/json"},
+	{verb: "POST", path: "/Echo", body: `{"message":"hi"}`, header: map[string]string{"Content-Type": "text/plain"}, code: 415, contentType: "application/json"},
+	{verb: "GET", path: "/Echo", code: 405, contentType: "application/json", allow: "POST"},
+	{verb: "PUT", path: "/Echo", code: 405, contentType: "application/json", allow: "POST"},
+	{verb: "POST", path: "/Echo", body: `{"message":"hi"}`, header: map[string]string{"X-Request-Id": "abc", "Request-Timeout": "soon"}, code: 400, contentType: "application/json",
+		want: `{"code":400,"message":"invalid Request-Timeout: time: invalid duration \"soon\"","request_id":"abc"}`},
+
+	{verb: "GET", path: "/Get/abc", code: 200, contentType: "application/json", want: `{"message":"abc"}`},
+	{verb: "GET", path: "/get/a%2Fb", code: 200, contentType: "application/json", want: `{"message":"a/b"}`},
+	{verb: "POST", path: "/Get", body: `{"id":"abc"}`, code: 200, contentType: "application/json", want: `{"message":"abc"}`},
+	{verb: "POST", path: "/Get/abc", code: 405, contentType: "application/json", allow: "GET"},
+	{verb: "GET", path: "/Get", code: 405, contentType: "application/json", allow: "POST"},
+
+	{verb: "GET", path: "/List", code: 200, contentType: "application/json", want: `[{"message":"a"},{"message":"b"}]`},
+	{verb: "POST", path: "/List", code: 200, contentType: "application/json", want: `[{"message":"a"},{"message":"b"}]`},
+	// Request-Timeout can't remove the deadline.
+	{verb: "POST", path: "/Echo", body: `{"message":"hi"}`, header: map[string]string{"Request-Timeout": "NaN"}, code: 400, contentType: "application/json",
+		want: `{"code":400,"message":"invalid Request-Timeout: must be positive"}`},
+	{verb: "POST", path: "/Echo", body: `{"message":"hi"}`, header: map[string]string{"Request-Timeout": "Inf"}, code: 400, contentType: "application/json",
+		want: `{"code":400,"message":"invalid Request-Timeout: must be at most 9223372036 seconds"}`},
+	{verb: "POST", path: "/Echo", body: `{"message":"hi"}`, header: map[string]string{"Request-Timeout": "1e300"}, code: 400, contentType: "application/json",
+		want: `{"code":400,"message":"invalid Request-Timeout: must be at most 9223372036 seconds"}`},
+	{verb: "POST", path: "/Delete", body: `{"id":"abc"}`, code: 204},
+	{verb: "POST", path: "/Fail", code: 404, contentType: "application/json", want: `{"code":404,"message":"nothing here"}`},
+
+	{verb: "POST", path: "/Count", body: `{"n":2}`, code: 200, contentType: "application/x-ndjson",
+		want: "{\"result\":{\"i\":0}}\n{\"result\":{\"i\":1}}\n"},
+	{verb: "POST", path: "/Count", body: `{"n":1}`, header: map[string]string{"Accept": "text/event-stream"}, code: 200, contentType: "text/event-stream",
+		want: "data: {\"i\":0}\n\nevent: end\ndata: {\"code\":200}\n\n"},
+
+	// both transports get the response of the first call.
+	{verb: "POST", path: "/Next", body: `{"step":1}`, header: map[string]string{"Idempotency-Key": "a"}, code: 200, contentType: "application/json",
+		want: `{"n":1}`},
+	{verb: "POST", path: "/Next", body: `{"step":1}`, header: map[string]string{"Idempotency-Key": "a"}, code: 200, contentType: "application/json",
+		want: `{"n":1}`},
+	{verb: "POST", path: "/Next", body: `{"step":2}`, header: map[string]string{"Idempotency-Key": "a"}, code: 422, contentType: "application/json",
+		want: `{"code":422,"message":"Idempotency-Key was used with another request"}`},
+	// the calls of a batch are kept apart.
+	{verb: "POST", path: "/_batch", body: `[{"method":"Next","input":{"step":1}},{"method":"Next","input":{"step":2}}]`, header: map[string]string{"Idempotency-Key": "b"}, code: 200, contentType: "application/json",
+		want: `[{"output":{"n":2},"status":{"code":200}},{"output":{"n":4},"status":{"code":200}}]`},
+	{verb: "POST", path: "/Next", body: `{"step":1}`, header: map[string]string{"Idempotency-Key": "b"}, code: 200, contentType: "application/json",
+		want: `{"n":5}`},
+
+	{verb: "POST", path: "/_batch", body: `[{"method":"Echo","input":{"message":"hi"}},{"method":"Nope"}]`, code: 200, contentType: "application/json",
+		want: `[{"output":{"message":"hi"},"status":{"code":200}},{"status":{"code":404,"message":"method not found: Nope"}}]`},
+	{verb: "GET", path: "/_batch", code: 405, contentType: "application/json", allow: "POST"},
+	{verb: "GET", path: "/_ws", code: 426, contentType: "application/json"},
+
+	{verb: "GET", path: "/Nope", code: 404, contentType: "application/json"},
+	{verb: "POST", path: "/Echo/help/more", code: 404, contentType: "application/json"},
+}
+
+// response is what a transport answered to a testCase.
+type response struct {
+	code        int
+	contentType string
+	allow       string
+	body        string
+}
+
+func main() {
+	rpc := fit.NewRPC(Service{},
+		fit.WithStrict(),
+		fit.WithMethod("Next", fit.Idempotent(fit.NewMemoryIdempotencyStore(time.Minute))),
+		fit.WithMethod("Get", fit.Get("/{id}")),
+		fit.WithMethod("List", fit.Get("")),
+	)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	rpc.MountFiberApp(app)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to listen: %v\n", err)
+		os.Exit(1)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	netHttp := httptest.NewServer(rpc.NewNetHttpHandler())
+	defer netHttp.Close()
+
+	transports := []struct {
+		name, url string
+	}{
+		{"fiber", "http://" + ln.Addr().String()},
+		{"net/http", netHttp.URL},
+	}
+
+	failed := 0
+	for _, tc := range cases {
+		var first *response
+		for _, transport := range transports {
+			resp, err := do(transport.url+"/"+rpc.Name(), tc)
+			if err == nil {
+				err = tc.check(resp)
+			}
+			if err == nil && first != nil && !first.equal(resp) {
+				err = fmt.Errorf("differs from %v: %+v != %+v", transports[0].name, resp, *first)
+			}
+			if err != nil {
+				failed++
+				fmt.Printf("FAIL %-8v %v %v: %v\n", transport.name, tc.verb, tc.path, err)
+				continue
+			}
+			fmt.Printf("ok   %-8v %v %v\n", transport.name, tc.verb, tc.path)
+			if first == nil {
+				first = resp
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("%v failed\n", failed)
+		os.Exit(1)
+	}
+}
+
+func do(prefix string, tc testCase) (*response, error) {
+	req, err := http.NewRequest(tc.verb, prefix+tc.path, strings.NewReader(tc.body))
+	if err != nil {
+		return nil, err
+	}
+	if tc.body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range tc.header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return &response{
+		code:        resp.StatusCode,
+		contentType: contentType,
+		allow:       resp.Header.Get("Allow"),
+		body:        string(body),
+	}, nil
+}
+
+func (tc testCase) check(resp *response) error {
+	switch {
+	case resp.code != tc.code:
+		return fmt.Errorf("got code %v, want %v: %v", resp.code, tc.code, resp.body)
+	case resp.contentType != tc.contentType:
+		return fmt.Errorf("got Content-Type %q, want %q", resp.contentType, tc.contentType)
+	case resp.allow != tc.allow:
+		return fmt.Errorf("got Allow %q, want %q", resp.allow, tc.allow)
+	case tc.code == http.StatusNoContent && resp.body != "":
+		return fmt.Errorf("got body %q, want none", resp.body)
+	case tc.want != "" && !sameBody(resp.contentType, resp.body, tc.want):
+		return fmt.Errorf("got body %q, want %q", resp.body, tc.want)
+	}
+	return nil
+}
+
+func (r *response) equal(other *response) bool {
+	return r.code == other.code &&
+		r.contentType == other.contentType &&
+		r.allow == other.allow &&
+		sameBody(r.contentType, r.body, other.body)
+}
+
+// sameBody compares JSON bodies by value, and others by bytes.
+func sameBody(contentType, a, b string) bool {
+	if contentType != "application/json" {
+		return a == b
+	}
+	var va, vb any
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return a == b
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return bytes.Equal(ja, jb)
+}
@@ -25,7 +25,7 @@ func main() {
 }
 
 type Users struct {
-	sync.RWMutex
+	mu     sync.RWMutex
 	lookup map[string]User
 }
 
@@ -46,8 +46,8 @@ type UserCreateResponse = User
 
 func (u *Users) Create(ctx context.Context, in *UserCreateRequest) (*UserCreateResponse, status.Status) {
 	out := *in
-	u.RLock()
-	defer u.RUnlock()
+	u.mu.Lock()
+	defer u.mu.Unlock()
 	u.lookup[in.ID] = out
 
 	return &out, status.OK
@@ -59,8 +59,8 @@ type UserGetRequest struct {
 type UserGetResponse = User
 
 func (u *Users) Get(ctx context.Context, in *UserGetRequest) (*UserGetResponse, status.Status) {
-	u.RLock()
-	defer u.RUnlock()
+	u.mu.RLock()
+	defer u.mu.RUnlock()
 	user, ok := u.lookup[in.ID]
 	if !ok {
 		return &user, status.NotFound
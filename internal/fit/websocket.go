@@ -0,0 +1,301 @@
+package fit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+	"github.com/valyala/fasthttp"
+)
+
+// WSRequest starts, or cancels, a call on a WebSocket. Many calls
+// can run at once on a connection, told apart by their ID.
+//
+//	GET /<type>/_ws
+//	{"id": 1, "method": "GetByUUID", "input": {"uuid": "..."}}
+//	{"id": 2, "method": "Watch", "input": {}}
+//	{"id": 2, "cancel": true}
+type WSRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method,omitempty"`
+	Input  json.RawMessage `json:"input,omitempty"`
+	// Cancel stops the call with ID.
+	Cancel bool `json:"cancel,omitempty"`
+}
+
+// WSResponse is sent for the OUTPUT of a call, once for unary
+// methods and for each OUTPUT of streaming ones. The last
+// response of a call is Done, with its Error if it failed.
+//
+//	{"id": 1, "output": {"uuid": "...", "email": "..."}, "done": true}
+//	{"id": 2, "output": {"op": "created", "user": {...}}}
+//	{"id": 2, "error": {"code": 408, "message": "..."}, "done": true}
+type WSResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Output any             `json:"output,omitempty"`
+	Error  *ErrorBody      `json:"error,omitempty"`
+	Done   bool            `json:"done,omitempty"`
+}
+
+const (
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsWriteWait  = 10 * time.Second
+)
+
+// DefaultMaxWebSocketCalls is the most calls a WebSocket may run
+// at once unless changed with WithMaxWebSocketCalls.
+const DefaultMaxWebSocketCalls = 100
+
+// DefaultMaxWebSocketMessageSize is the largest message, in bytes,
+// a WebSocket may receive unless changed with
+// WithMaxWebSocketMessageSize. It is the default body limit of
+// fiber.
+const DefaultMaxWebSocketMessageSize = 4 << 20
+
+// WithMaxWebSocketCalls limits the number of calls a WebSocket
+// runs at once. More are rejected with codes.TooManyRequests. A
+// limit <= 0 allows any number.
+func WithMaxWebSocketCalls(n int) Option {
+	return func(r *RPC) {
+		r.wsMaxCalls = n
+	}
+}
+
+// WithMaxWebSocketMessageSize limits the size of the messages a
+// WebSocket receives. It is closed on larger ones. A limit <= 0
+// allows any size.
+func WithMaxWebSocketMessageSize(n int64) Option {
+	return func(r *RPC) {
+		r.wsMaxMessageSize = n
+	}
+}
+
+// WithWebSocketOrigins allows WebSockets to be opened from pages
+// of other origins, e.g. https://dashboard.example.com. By default
+// only the same origin is allowed.
+func WithWebSocketOrigins(origins ...string) Option {
+	return func(r *RPC) {
+		for _, origin := range origins {
+			r.wsOrigins[origin] = true
+		}
+	}
+}
+
+func (s *RPC) fiberWS(c *fiber.Ctx) error {
+	if !websocket.FastHTTPIsWebSocketUpgrade(c.Context()) {
+		c.Set(fiber.HeaderUpgrade, "websocket")
+		return sendFiberError(c, status.New(codes.UpgradeRequired, "expected a websocket upgrade"))
+	}
+	ctx, _, cancel := fiberContext(c, TransportWebSocket)
+	upgrader := websocket.FastHTTPUpgrader{}
+	if len(s.wsOrigins) > 0 {
+		upgrader.CheckOrigin = func(c *fasthttp.RequestCtx) bool {
+			return s.wsOrigins[string(c.Request.Header.Peek(fiber.HeaderOrigin))]
+		}
+	}
+	err := upgrader.Upgrade(c.Context(), func(conn *websocket.Conn) {
+		defer cancel()
+		s.serveWS(ctx, conn)
+	})
+	if err != nil {
+		cancel()
+	}
+	return err
+}
+
+func (s *RPC) netHttpWS(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		w.Header().Set("Upgrade", "websocket")
+		writeHTTPError(w, r, status.New(codes.UpgradeRequired, "expected a websocket upgrade"))
+		return
+	}
+	upgrader := websocket.Upgrader{}
+	if len(s.wsOrigins) > 0 {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			return s.wsOrigins[r.Header.Get("Origin")]
+		}
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already responded.
+		return
+	}
+	ctx, _ := httpContext(w, r, TransportWebSocket)
+	s.serveWS(ctx, conn)
+}
+
+// wsConn serves the calls of a single WebSocket.
+type wsConn struct {
+	rpc  *RPC
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+
+	mu    sync.Mutex
+	calls map[string]context.CancelFunc
+	wg    sync.WaitGroup
+}
+
+func (s *RPC) serveWS(ctx context.Context, conn *websocket.Conn) {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &wsConn{
+		rpc:   s,
+		conn:  conn,
+		calls: make(map[string]context.CancelFunc),
+	}
+	defer func() {
+		cancel()
+		c.wg.Wait()
+		conn.Close()
+	}()
+
+	if s.wsMaxMessageSize > 0 {
+		conn.SetReadLimit(s.wsMaxMessageSize)
+	}
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	go c.ping(ctx)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req WSRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.fail(json.RawMessage("null"), status.Newf(codes.BadRequest, "failed to decode request: %v", err))
+			continue
+		}
+		c.handle(ctx, req)
+	}
+}
+
+func (c *wsConn) ping(ctx context.Context) {
+	t := time.NewTicker(wsPingPeriod)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			c.writeMu.Lock()
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+			c.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *wsConn) handle(ctx context.Context, req WSRequest) {
+	id := string(req.ID)
+	if len(req.ID) == 0 {
+		req.ID = json.RawMessage("null")
+	}
+	if req.Cancel {
+		c.mu.Lock()
+		if cancel, ok := c.calls[id]; ok {
+			cancel()
+		}
+		c.mu.Unlock()
+		return
+	}
+
+	method, ok := c.rpc.methods[req.Method]
+	if !ok {
+		c.fail(req.ID, status.Newf(codes.NotFound, "method not found: %v", req.Method))
+		return
+	}
+	in := method.newIn()
+	if len(req.Input) > 0 {
+		if err := c.rpc.codecs["application/json"].Unmarshal(req.Input, in); err != nil {
+			c.fail(req.ID, status.Newf(codes.BadRequest, "failed to decode input: %v", err))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	if _, ok := c.calls[id]; ok {
+		c.mu.Unlock()
+		cancel()
+		c.fail(req.ID, status.Newf(codes.Conflict, "id %v is already in use", id))
+		return
+	}
+	if limit := c.rpc.wsMaxCalls; limit > 0 && len(c.calls) >= limit {
+		c.mu.Unlock()
+		cancel()
+		c.fail(req.ID, status.Newf(codes.TooManyRequests, "too many calls: the limit is %v per connection", limit))
+		return
+	}
+	c.calls[id] = cancel
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.calls, id)
+			c.mu.Unlock()
+			cancel()
+			c.wg.Done()
+		}()
+		if method.stream != unary {
+			c.stream(ctx, req.ID, method, in)
+			return
+		}
+		out, st := c.rpc.call(ctx, method, in)
+		if st.Code >= 300 {
+			c.fail(req.ID, st)
+			return
+		}
+		if st.Code == codes.NoContent {
+			out = nil
+		}
+		c.send(WSResponse{ID: req.ID, Output: out, Done: true})
+	}()
+}
+
+func (c *wsConn) stream(ctx context.Context, id json.RawMessage, method *Method, in any) {
+	for ev := range c.rpc.openStream(ctx, method, in) {
+		switch {
+		case ev.open:
+		case ev.done && ev.st.Code >= 300:
+			c.fail(id, ev.st)
+		case ev.done:
+			c.send(WSResponse{ID: id, Done: true})
+		default:
+			c.send(WSResponse{ID: id, Output: ev.out})
+		}
+	}
+}
+
+func (c *wsConn) fail(id json.RawMessage, st status.Status) {
+	c.send(WSResponse{ID: id, Error: wsErrorBody(st), Done: true})
+}
+
+func (c *wsConn) send(resp WSResponse) {
+	data, err := c.rpc.codecs["application/json"].Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(WSResponse{ID: resp.ID, Error: wsErrorBody(status.Newf(codes.Internal, "failed to encode response: %v", err)), Done: true})
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func wsErrorBody(st status.Status) *ErrorBody {
+	body := newErrorBody(st, "")
+	return &body
+}
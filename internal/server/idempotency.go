@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyqe/ribose/internal/database"
+	"github.com/hyqe/ribose/internal/fit"
+	"github.com/hyqe/ribose/internal/fit/codes"
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// idempotencyStore keeps the responses of fit.Idempotent methods
+// in the idempotency_keys table for ttl, so every instance
+// replays them.
+type idempotencyStore struct {
+	q   *database.Queries
+	ttl time.Duration
+
+	mu        sync.Mutex
+	nextSweep time.Time
+}
+
+func newIdempotencyStore(q *database.Queries, ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		q:   q,
+		ttl: ttl,
+	}
+}
+
+// claimAttempts bounds how often Claim retries a key which was
+// released between claiming and reading it.
+const claimAttempts = 3
+
+func (s *idempotencyStore) Claim(ctx context.Context, key fit.IdempotencyKey, requestHash []byte) (*fit.IdempotencyRecord, error) {
+	s.sweep(ctx)
+	for i := 0; i < claimAttempts; i++ {
+		claimed, err := s.q.ClaimIdempotencyKey(ctx, database.ClaimIdempotencyKeyParams{
+			Principal:   key.Principal,
+			Method:      key.Method,
+			Key:         key.Key,
+			BatchItem:   int32(key.BatchItem),
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(s.ttl),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if claimed > 0 {
+			return nil, nil
+		}
+		row, err := s.q.GetIdempotencyKey(ctx, database.GetIdempotencyKeyParams{
+			Principal: key.Principal,
+			Method:    key.Method,
+			Key:       key.Key,
+			BatchItem: int32(key.BatchItem),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return idempotencyRecord(row)
+	}
+	return nil, fmt.Errorf("%v was released %v times while claiming it", key.Key, claimAttempts)
+}
+
+func (s *idempotencyStore) Complete(ctx context.Context, key fit.IdempotencyKey, st status.Status, output json.RawMessage) error {
+	details, err := json.Marshal(st.Details())
+	if err != nil {
+		return fmt.Errorf("failed to encode details: %v", err)
+	}
+	return s.q.CompleteIdempotencyKey(ctx, database.CompleteIdempotencyKeyParams{
+		Principal:     key.Principal,
+		Method:        key.Method,
+		Key:           key.Key,
+		BatchItem:     int32(key.BatchItem),
+		StatusCode:    int32(st.Code),
+		StatusMessage: st.Message,
+		StatusDetails: details,
+		Output:        output,
+	})
+}
+
+func (s *idempotencyStore) Release(ctx context.Context, key fit.IdempotencyKey) error {
+	return s.q.DeleteIdempotencyKey(ctx, database.DeleteIdempotencyKeyParams{
+		Principal: key.Principal,
+		Method:    key.Method,
+		Key:       key.Key,
+		BatchItem: int32(key.BatchItem),
+	})
+}
+
+// sweep deletes the expired keys, at most once per ttl. Claim
+// takes them over anyway, so it fails quietly.
+func (s *idempotencyStore) sweep(ctx context.Context) {
+	s.mu.Lock()
+	now := time.Now()
+	due := now.After(s.nextSweep)
+	if due {
+		s.nextSweep = now.Add(s.ttl)
+	}
+	s.mu.Unlock()
+	if due {
+		s.q.DeleteExpiredIdempotencyKeys(ctx)
+	}
+}
+
+func idempotencyRecord(row database.IdempotencyKey) (*fit.IdempotencyRecord, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(row.StatusDetails, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode details: %v", err)
+	}
+	details := make([]any, 0, len(raw))
+	for _, data := range raw {
+		detail, err := status.DecodeDetail(data)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, detail)
+	}
+	st := status.New(codes.Code(row.StatusCode), row.StatusMessage)
+	if len(details) > 0 {
+		st = st.WithDetails(details...)
+	}
+	return &fit.IdempotencyRecord{
+		RequestHash: row.RequestHash,
+		Done:        row.Done,
+		Status:      st,
+		Output:      row.Output,
+	}, nil
+}
@@ -47,8 +47,22 @@ func Run(ctx context.Context) {
 	queries := database.New(db)
 
 	userSvc := users.NewService(queries)
+	idempotency := newIdempotencyStore(queries, 24*time.Hour)
 
-	fit.NewRPC(userSvc).MountFiberApp(app)
+	userRPC := fit.NewRPC(userSvc,
+		fit.WithStrict(),
+		fit.WithDefaultTimeout(10*time.Second),
+		fit.WithMethod("Create", fit.Idempotent(idempotency)),
+		fit.WithMethod("GetByUUID", fit.Get("/{uuid}")),
+		fit.WithMethod("GetByEmail", fit.Get("")),
+		fit.WithMethod("Watch", fit.Get("")),
+	)
+
+	rpcs, err := fit.NewServer(userRPC)
+	if err != nil {
+		log.Fatalf("failed to build server: %v", err)
+	}
+	rpcs.MountFiberApp(app)
 
 	go app.Listen(cfg.Addr())
 
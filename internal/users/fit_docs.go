@@ -0,0 +1,29 @@
+// Code generated by fitdoc. DO NOT EDIT.
+
+package users
+
+import "github.com/hyqe/ribose/internal/fit"
+
+func init() {
+	fit.RegisterDocs("github.com/hyqe/ribose/internal/users", map[string]string{
+		"Change":                   "Change is made to a user by Create, UpdateByUUID or DeleteByUUID.",
+		"Change.Op":                "Op is what was done to the user.",
+		"Change.User":              "User is the user after the change, or only its UUID once\ndeleted.",
+		"CreateRequest.Email":      "Email must not belong to another user.",
+		"DeleteByUUIDRequest.UUID": "UUID is the user to delete.",
+		"GetByEmailRequest.Email":  "Email of the user to get.",
+		"GetByUUIDRequest.UUID":    "UUID of the user to get.",
+		"Service":                  "Service manages users, and streams the changes made to them to\nwhoever watches.",
+		"Service.Create":           "Create adds a user with a new UUID.",
+		"Service.DeleteByUUID":     "DeleteByUUID deletes a user. It succeeds when there is no such\nuser.",
+		"Service.GetByEmail":       "GetByEmail gets the user with an email.",
+		"Service.GetByUUID":        "GetByUUID gets the user with a UUID.",
+		"Service.UpdateByUUID":     "UpdateByUUID changes the email of a user.",
+		"Service.Watch":            "Watch sends every change made to users by this instance until\nthe client goes away.",
+		"UpdateByUUIDRequest.UUID": "UUID is the user to update.",
+		"UpdateByUUIDRequest.User": "User is what to update it to. Its UUID is ignored.",
+		"User":                     "User is an account, identified by its UUID.",
+		"User.Email":               "Email is unique among users.",
+		"User.UUID":                "UUID is assigned when the user is created.",
+	})
+}
@@ -2,7 +2,10 @@ package users
 
 import "github.com/google/uuid"
 
+// User is an account, identified by its UUID.
 type User struct {
-	UUID  uuid.UUID `json:"uuid"`
-	Email string    `json:"email" validate:"email" example:"foo@example.com"`
+	// UUID is assigned when the user is created.
+	UUID uuid.UUID `json:"uuid"`
+	// Email is unique among users.
+	Email string `json:"email" validate:"email" example:"foo@example.com"`
 }
@@ -1,3 +1,5 @@
+//go:generate go run github.com/hyqe/ribose/cmd/fitdoc
+
 package users
 
 import (
@@ -9,62 +11,79 @@ import (
 	"github.com/hyqe/ribose/internal/fit/status"
 )
 
+// Service manages users, and streams the changes made to them to
+// whoever watches.
 type Service struct {
-	db *database.Queries
+	db      *database.Queries
+	changes *changeFeed
 }
 
 func NewService(db *database.Queries) *Service {
 	return &Service{
-		db: db,
+		db:      db,
+		changes: newChangeFeed(),
 	}
 }
 
 type CreateRequest struct {
+	// Email must not belong to another user.
 	Email string `json:"email" validate:"email" example:"foo@example.com"`
 }
 type CreateResponse = User
 
+// Create adds a user with a new UUID.
 func (s *Service) Create(ctx context.Context, in *CreateRequest) (*CreateResponse, status.Status) {
 	switch u, err := s.db.CreateUsers(ctx, in.Email); err {
 	case nil:
-		return &CreateResponse{
+		out := &CreateResponse{
 			UUID:  u.Uuid,
 			Email: u.Email,
-		}, status.OK
+		}
+		s.changes.publish("created", *out)
+		return out, status.OK
 	default:
 		return nil, status.New(codes.Internal, err)
 	}
 }
 
-type UpdateByUUIDRequest = struct {
+type UpdateByUUIDRequest struct {
+	// UUID is the user to update.
 	UUID uuid.UUID `json:"uuid"`
+	// User is what to update it to. Its UUID is ignored.
 	User `json:"user"`
 }
 type UpdateByUUIDResponse = User
 
+// UpdateByUUID changes the email of a user.
 func (s *Service) UpdateByUUID(ctx context.Context, in *UpdateByUUIDRequest) (*UpdateByUUIDResponse, status.Status) {
 	switch u, err := s.db.UpdateUserByUUID(ctx, database.UpdateUserByUUIDParams{
 		Uuid:  in.UUID,
 		Email: in.User.Email,
 	}); err {
 	case nil:
-		return &UpdateByUUIDResponse{
+		out := &UpdateByUUIDResponse{
 			UUID:  u.Uuid,
 			Email: u.Email,
-		}, status.OK
+		}
+		s.changes.publish("updated", *out)
+		return out, status.OK
 	default:
 		return nil, status.New(codes.Internal, err)
 	}
 }
 
 type DeleteByUUIDRequest struct {
+	// UUID is the user to delete.
 	UUID uuid.UUID `json:"uuid"`
 }
 type DeleteByUUIDResponse struct{}
 
+// DeleteByUUID deletes a user. It succeeds when there is no such
+// user.
 func (s *Service) DeleteByUUID(ctx context.Context, in *DeleteByUUIDRequest) (*DeleteByUUIDResponse, status.Status) {
 	switch err := s.db.DeleteUser(ctx, in.UUID); err {
 	case nil:
+		s.changes.publish("deleted", User{UUID: in.UUID})
 		return &DeleteByUUIDResponse{}, status.OK
 	default:
 		return nil, status.New(codes.Internal, err)
@@ -72,10 +91,12 @@ func (s *Service) DeleteByUUID(ctx context.Context, in *DeleteByUUIDRequest) (*D
 }
 
 type GetByEmailRequest struct {
+	// Email of the user to get.
 	Email string `json:"email" validate:"email" example:"foo@example.com"`
 }
 type GetByEmailResponse = User
 
+// GetByEmail gets the user with an email.
 func (s *Service) GetByEmail(ctx context.Context, in *GetByEmailRequest) (*GetByEmailResponse, status.Status) {
 	switch u, err := s.db.GetUserByEmail(ctx, in.Email); err {
 	case nil:
@@ -89,10 +110,12 @@ func (s *Service) GetByEmail(ctx context.Context, in *GetByEmailRequest) (*GetBy
 }
 
 type GetByUUIDRequest struct {
-	UUID uuid.UUID `json:"uuid"`
+	// UUID of the user to get.
+	UUID uuid.UUID `json:"uuid" path:"uuid"`
 }
 type GetByUUIDResponse = User
 
+// GetByUUID gets the user with a UUID.
 func (s *Service) GetByUUID(ctx context.Context, in *GetByUUIDRequest) (*GetByUUIDResponse, status.Status) {
 	switch u, err := s.db.GetUserByUUID(ctx, in.UUID); err {
 	case nil:
@@ -0,0 +1,73 @@
+package users
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hyqe/ribose/internal/fit/status"
+)
+
+// Change is made to a user by Create, UpdateByUUID or DeleteByUUID.
+type Change struct {
+	// Op is what was done to the user.
+	Op string `json:"op" validate:"oneof=created updated deleted"`
+	// User is the user after the change, or only its UUID once
+	// deleted.
+	User User `json:"user"`
+}
+
+type WatchRequest struct{}
+
+// Watch sends every change made to users by this instance until
+// the client goes away.
+func (s *Service) Watch(ctx context.Context, in *WatchRequest, send func(*Change) error) status.Status {
+	changes, unsubscribe := s.changes.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.OK
+		case change := <-changes:
+			if err := send(&change); err != nil {
+				return status.OK
+			}
+		}
+	}
+}
+
+// changeFeed fans changes out to every Watch.
+type changeFeed struct {
+	mu   sync.Mutex
+	subs map[chan Change]struct{}
+}
+
+func newChangeFeed() *changeFeed {
+	return &changeFeed{
+		subs: make(map[chan Change]struct{}),
+	}
+}
+
+func (f *changeFeed) subscribe() (<-chan Change, func()) {
+	ch := make(chan Change, 16)
+	f.mu.Lock()
+	f.subs[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.subs, ch)
+		f.mu.Unlock()
+	}
+}
+
+// publish drops the change for subscribers too slow to keep up,
+// rather than blocking the write that made it.
+func (f *changeFeed) publish(op string, u User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- Change{Op: op, User: u}:
+		default:
+		}
+	}
+}